@@ -9,6 +9,7 @@ import (
 
 	"github.com/3scale/ostia/ostia-operator/pkg/apis"
 	"github.com/3scale/ostia/ostia-operator/pkg/controller"
+	"github.com/3scale/ostia/ostia-operator/pkg/webhook"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
@@ -105,6 +106,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup all admission Webhooks, they can only be served from inside the cluster
+	operatorNamespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		log.Info("Not running in a cluster, admission webhooks are disabled", "Error", err.Error())
+	} else if err := webhook.AddToManager(mgr, operatorNamespace); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Create Service object to expose the metrics port.
 	_, err = metrics.ExposeMetricsPort(ctx, metricsPort)
 	if err != nil {
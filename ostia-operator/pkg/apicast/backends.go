@@ -0,0 +1,109 @@
+package apicast
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/3scale/ostia/ostia-operator/pkg/apicast/standalone"
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReferencedBackends returns the Services called by the Endpoints of the API
+func ReferencedBackends(api *ostia.API) []types.NamespacedName {
+	var names []types.NamespacedName
+	seen := make(map[types.NamespacedName]bool)
+
+	for _, endpoint := range api.Spec.Endpoints {
+		if endpoint.BackendRef == nil {
+			continue
+		}
+		name := types.NamespacedName{Name: endpoint.BackendRef.Name, Namespace: standalone.BackendNamespace(api, endpoint.BackendRef)}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// fetchBackends returns the referenced Services that exist, those outside watchNamespace can't be read
+func fetchBackends(client client.Client, api *ostia.API, watchNamespace string) (map[types.NamespacedName]*v1.Service, error) {
+	services := make(map[types.NamespacedName]*v1.Service)
+
+	for _, name := range ReferencedBackends(api) {
+		if watchNamespace != "" && name.Namespace != watchNamespace {
+			continue
+		}
+
+		service := &v1.Service{}
+		if err := client.Get(context.TODO(), name, service); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return services, err
+		}
+		services[name] = service
+	}
+
+	return services, nil
+}
+
+// backendsResolvedCondition checks every BackendRef points to an existing Service port
+func backendsResolvedCondition(api *ostia.API, services map[types.NamespacedName]*v1.Service, watchNamespace string) ostia.APICondition {
+	var problems []string
+	reason := "BackendsFound"
+
+	for _, endpoint := range api.Spec.Endpoints {
+		ref := endpoint.BackendRef
+		if ref == nil {
+			continue
+		}
+		name := types.NamespacedName{Name: ref.Name, Namespace: standalone.BackendNamespace(api, ref)}
+
+		service, ok := services[name]
+		switch {
+		case watchNamespace != "" && name.Namespace != watchNamespace:
+			reason = "NamespaceNotWatched"
+			problems = append(problems, fmt.Sprintf("%s: namespace %s is not watched by the operator", endpoint.Name, name.Namespace))
+		case !ok:
+			reason = "ServiceNotFound"
+			problems = append(problems, fmt.Sprintf("%s: service %s not found", endpoint.Name, name))
+		case !hasServicePort(service, ref.Port):
+			reason = "PortNotFound"
+			problems = append(problems, fmt.Sprintf("%s: port %d not found in service %s", endpoint.Name, ref.Port, name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return ostia.APICondition{
+			Type:    ostia.ConditionBackendsResolved,
+			Status:  v1.ConditionFalse,
+			Reason:  reason,
+			Message: strings.Join(problems, ", "),
+		}
+	}
+
+	return ostia.APICondition{
+		Type:    ostia.ConditionBackendsResolved,
+		Status:  v1.ConditionTrue,
+		Reason:  reason,
+		Message: "Every backend service was found",
+	}
+}
+
+func hasServicePort(service *v1.Service, port int32) bool {
+	for _, p := range service.Spec.Ports {
+		if p.Port == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package apicast
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBackendsResolvedCondition(t *testing.T) {
+	api := &ostia.API{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "apis"},
+		Spec: ostia.APISpec{Endpoints: []ostia.Endpoint{
+			{Name: "orders", BackendRef: &ostia.BackendRef{Name: "orders", Port: 8080}},
+			{Name: "orders-admin", BackendRef: &ostia.BackendRef{Name: "orders", Port: 9090}},
+			{Name: "carts", BackendRef: &ostia.BackendRef{Name: "carts", Namespace: "shop", Port: 80}},
+			{Name: "hello", Host: "https://echo-api.3scale.net"},
+		}},
+	}
+
+	orders := types.NamespacedName{Name: "orders", Namespace: "apis"}
+	carts := types.NamespacedName{Name: "carts", Namespace: "shop"}
+
+	if backends := ReferencedBackends(api); len(backends) != 2 || backends[0] != orders || backends[1] != carts {
+		t.Errorf("unexpected referenced backends - %v", backends)
+	}
+
+	service := func(ports ...int32) *v1.Service {
+		s := &v1.Service{}
+		for _, port := range ports {
+			s.Spec.Ports = append(s.Spec.Ports, v1.ServicePort{Port: port})
+		}
+		return s
+	}
+
+	inputs := []struct {
+		services       map[types.NamespacedName]*v1.Service
+		watchNamespace string
+		expectStatus   v1.ConditionStatus
+		expectMessage  string
+	}{
+		{
+			services:     map[types.NamespacedName]*v1.Service{orders: service(8080, 9090), carts: service(80)},
+			expectStatus: v1.ConditionTrue,
+		},
+		{
+			services:      map[types.NamespacedName]*v1.Service{orders: service(8080)},
+			expectStatus:  v1.ConditionFalse,
+			expectMessage: "carts: service shop/carts not found, orders-admin: port 9090 not found in service apis/orders",
+		},
+		{
+			services:       map[types.NamespacedName]*v1.Service{orders: service(8080, 9090)},
+			watchNamespace: "apis",
+			expectStatus:   v1.ConditionFalse,
+			expectMessage:  "carts: namespace shop is not watched by the operator",
+		},
+	}
+
+	for _, input := range inputs {
+		condition := backendsResolvedCondition(api, input.services, input.watchNamespace)
+		if condition.Status != input.expectStatus || (input.expectMessage != "" && condition.Message != input.expectMessage) {
+			t.Errorf("expected %s - %s, got %#v", input.expectStatus, input.expectMessage, condition)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package apicast
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
 	extensions "k8s.io/api/extensions/v1beta1"
@@ -13,8 +16,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
-	b64 "encoding/base64"
-
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
@@ -23,6 +24,20 @@ var log = logf.Log.WithName("apicast")
 const (
 	defaultApicastImage   = "quay.io/3scale/apicast"
 	defaultApicastVersion = "master"
+
+	// tlsSecretVersionAnnotation holds the resourceVersion of the GatewayTLS Secret mounted in the pods
+	tlsSecretVersionAnnotation = "ostia.3scale.net/tls-secret-version"
+	// endpointClientVersionAnnotation holds the resourceVersion of the client certificate Secret of an Endpoint
+	endpointClientVersionAnnotation = "ostia.3scale.net/endpoint-%d-client-version"
+	// endpointCAVersionAnnotation holds the resourceVersion of the CA bundle ConfigMap of an Endpoint
+	endpointCAVersionAnnotation = "ostia.3scale.net/endpoint-%d-ca-version"
+	// configChecksumAnnotation holds the checksum of the configuration mounted in the pods
+	configChecksumAnnotation = "ostia.3scale.net/config-checksum"
+
+	// configDir is where the configuration Secret is mounted in the APIcast container
+	configDir = "/etc/ostia/config"
+	// configKey is the key of the configuration in the configuration Secret
+	configKey = "config.json"
 )
 
 var apicastImage = getProxyImageVersion()
@@ -32,18 +47,76 @@ func labelsForAPIcast(name string) map[string]string {
 	return map[string]string{"app": "apicast", "apiRef": name}
 }
 
-func toDataURI(mime string, str []byte) string {
-	return fmt.Sprintf("data:%s;base64,%s", mime, b64.URLEncoding.EncodeToString(str))
+// ConfigSecret returns the Secret holding the APIcast configuration, which carries the API keys and other
+// values read from the referenced Secrets, so it is mounted instead of being set in the pod spec
+func ConfigSecret(api *ostia.API, config []byte) *v1.Secret {
+	secret := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configSecretName(api),
+			Namespace: api.Namespace,
+			Labels:    labelsForAPIcast(api.Name),
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{configKey: config},
+	}
+
+	addOwnerRefToObject(secret, asOwner(api))
+	return secret
 }
 
-// DeploymentConfig returns an openshift deploymentConfig object for APIcast
-func DeploymentConfig(api *ostia.API) (*appsv1.Deployment, error) {
+// DeploymentConfig returns an openshift deploymentConfig object for APIcast, and the Secret with the configuration it mounts
+func DeploymentConfig(api *ostia.API, resources standalone.Resources) (*appsv1.Deployment, *v1.Secret, error) {
 	apicastLabels := labelsForAPIcast(api.Name)
-	apicastConfig, err := standalone.CreateConfig(api)
+	apicastConfig, err := standalone.CreateConfig(api, resources)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	configSecret := ConfigSecret(api, apicastConfig)
 	apicastName := apicastName(api)
+	volumes, volumeMounts := endpointVolumes(api)
+	volumes = append(volumes, v1.Volume{
+		Name:         "config",
+		VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: configSecret.Name}},
+	})
+	volumeMounts = append(volumeMounts, v1.VolumeMount{Name: "config", MountPath: configDir, ReadOnly: true})
+	ports := []v1.ContainerPort{
+		{ContainerPort: 8080, Name: "proxy", Protocol: "TCP"},
+		{ContainerPort: 8090, Name: "management", Protocol: "TCP"},
+	}
+	env := []v1.EnvVar{
+		{Name: "APICAST_LOG_LEVEL", Value: "debug"},
+		{Name: "APICAST_ENVIRONMENT", Value: "standalone"},
+		{Name: "APICAST_CONFIGURATION", Value: "file://" + path.Join(configDir, configKey)},
+	}
+	// The content caching policy leaves how long responses are kept to nginx
+	if maxTime := standalone.CacheMaxTime(api); maxTime != "" {
+		env = append(env, v1.EnvVar{Name: "APICAST_CACHE_MAX_TIME", Value: maxTime})
+	}
+	// APIcast only reads the configuration on start, so changing it has to roll out new pods
+	podAnnotations := map[string]string{configChecksumAnnotation: fmt.Sprintf("%x", sha256.Sum256(apicastConfig))}
+	for key, version := range endpointTLSVersions(api, resources) {
+		podAnnotations[key] = version
+	}
+
+	if tls := api.Spec.TLS; tls != nil && tls.Listener {
+		ports = append(ports, v1.ContainerPort{ContainerPort: standalone.HTTPSPort, Name: "https", Protocol: "TCP"})
+		volumes = append(volumes, v1.Volume{
+			Name:         "gateway-tls",
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: tls.SecretName}},
+		})
+		volumeMounts = append(volumeMounts, v1.VolumeMount{Name: "gateway-tls", MountPath: standalone.GatewayTLSDir, ReadOnly: true})
+
+		// APIcast only reads the certificate on start, so renewing it has to roll out new pods
+		secret, ok := resources.Secrets[tls.SecretName]
+		if !ok {
+			return nil, nil, fmt.Errorf("secret %s of the gateway tls not found", tls.SecretName)
+		}
+		podAnnotations[tlsSecretVersionAnnotation] = secret.ResourceVersion
+	}
 
 	deploymentConfig := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
@@ -51,9 +124,10 @@ func DeploymentConfig(api *ostia.API) (*appsv1.Deployment, error) {
 			Kind:       "Deployment",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      apicastName,
-			Namespace: api.Namespace,
-			Labels:    apicastLabels,
+			Name:        apicastName,
+			Namespace:   api.Namespace,
+			Labels:      apicastLabels,
+			Annotations: map[string]string{generationAnnotation: strconv.FormatInt(api.Generation, 10)},
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: nil,
@@ -69,6 +143,7 @@ func DeploymentConfig(api *ostia.API) (*appsv1.Deployment, error) {
 						"deployment": apicastName,
 						"app":        "apicast",
 					},
+					Annotations: podAnnotations,
 				},
 				Spec: v1.PodSpec{
 					Containers: []v1.Container{
@@ -76,25 +151,66 @@ func DeploymentConfig(api *ostia.API) (*appsv1.Deployment, error) {
 							Image:           apicastImage,
 							ImagePullPolicy: v1.PullAlways,
 							Name:            "apicast",
-							Ports: []v1.ContainerPort{
-								{ContainerPort: 8080, Name: "proxy", Protocol: "TCP"},
-								{ContainerPort: 8090, Name: "management", Protocol: "TCP"},
-							},
-							Env: []v1.EnvVar{
-								{Name: "APICAST_LOG_LEVEL", Value: "debug"},
-								{Name: "APICAST_ENVIRONMENT", Value: "standalone"},
-								{Name: "APICAST_CONFIGURATION", Value: toDataURI("application/json", apicastConfig)},
-							},
+							Ports:           ports,
+							Env:             env,
 							LivenessProbe:  newHTTPProbe("/status/live", 8090, 10, 5, 10),
 							ReadinessProbe: newTCPProbe(8080, 15, 5, 30), // standalone management API does not support this
+							VolumeMounts:   volumeMounts,
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
 	}
 	addOwnerRefToObject(deploymentConfig, asOwner(api))
-	return deploymentConfig, nil
+	return deploymentConfig, configSecret, nil
+}
+
+// endpointVolumes mounts the Secrets referenced by the Endpoints where the APIcast configuration expects them
+func endpointVolumes(api *ostia.API) ([]v1.Volume, []v1.VolumeMount) {
+	var volumes []v1.Volume
+	var mounts []v1.VolumeMount
+
+	for i, endpoint := range api.Spec.Endpoints {
+		// The CA bundle is rendered in the configuration, only the client certificate is mounted
+		if endpoint.TLS != nil && endpoint.TLS.ClientCertificateRef != nil {
+			ref := endpoint.TLS.ClientCertificateRef
+			name := fmt.Sprintf("endpoint-%d-client", i)
+			volumes = append(volumes, v1.Volume{
+				Name:         name,
+				VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: ref.Name}},
+			})
+			mounts = append(mounts, v1.VolumeMount{Name: name, MountPath: standalone.UpstreamClientCertificateDir(endpoint.Name), ReadOnly: true})
+		}
+	}
+
+	return volumes, mounts
+}
+
+// endpointTLSVersions returns the annotations with the resourceVersion of the TLS Secrets and ConfigMaps
+// referenced by the Endpoints, so renewing a certificate or a CA bundle rolls out new pods
+func endpointTLSVersions(api *ostia.API, resources standalone.Resources) map[string]string {
+	annotations := make(map[string]string)
+
+	// The annotations are keyed by the Endpoint position, as names could exceed the length allowed in keys
+	for i, endpoint := range api.Spec.Endpoints {
+		if endpoint.TLS == nil {
+			continue
+		}
+		if ref := endpoint.TLS.ClientCertificateRef; ref != nil {
+			if secret, ok := resources.Secrets[ref.Name]; ok {
+				annotations[fmt.Sprintf(endpointClientVersionAnnotation, i)] = secret.ResourceVersion
+			}
+		}
+		if ref := endpoint.TLS.CABundleRef; ref != nil {
+			if configMap, ok := resources.ConfigMaps[ref.Name]; ok {
+				annotations[fmt.Sprintf(endpointCAVersionAnnotation, i)] = configMap.ResourceVersion
+			}
+		}
+	}
+
+	return annotations
 }
 
 // Service returns a k8s service object for APIcast
@@ -103,6 +219,14 @@ func Service(api *ostia.API) *v1.Service {
 	apicastLabels := labelsForAPIcast(api.Name)
 	apicastName := apicastName(api)
 
+	ports := []v1.ServicePort{
+		{Name: "proxy", Port: 8080, Protocol: "TCP", TargetPort: intstr.FromInt(8080)},
+		{Name: "management", Port: 8090, Protocol: "TCP", TargetPort: intstr.FromInt(8090)},
+	}
+	if tls := api.Spec.TLS; tls != nil && tls.Listener {
+		ports = append(ports, v1.ServicePort{Name: "https", Port: standalone.HTTPSPort, Protocol: "TCP", TargetPort: intstr.FromInt(standalone.HTTPSPort)})
+	}
+
 	service := &v1.Service{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
@@ -114,10 +238,7 @@ func Service(api *ostia.API) *v1.Service {
 			Labels:    apicastLabels,
 		},
 		Spec: v1.ServiceSpec{
-			Ports: []v1.ServicePort{
-				{Name: "proxy", Port: 8080, Protocol: "TCP", TargetPort: intstr.FromInt(8080)},
-				{Name: "management", Port: 8090, Protocol: "TCP", TargetPort: intstr.FromInt(8090)},
-			},
+			Ports: ports,
 			Selector: map[string]string{
 				"deployment": apicastName, "app": "apicast",
 			},
@@ -164,6 +285,12 @@ func Ingress(api *ostia.API) *extensions.Ingress {
 		},
 	}
 
+	if tls := api.Spec.TLS; tls != nil {
+		ingress.Spec.TLS = []extensions.IngressTLS{
+			{Hosts: []string{api.Spec.Hostname}, SecretName: tls.SecretName},
+		}
+	}
+
 	addOwnerRefToObject(ingress, asOwner(api))
 
 	return ingress
@@ -188,6 +315,10 @@ func apicastName(api *ostia.API) string {
 	return "apicast-" + api.Name
 }
 
+func configSecretName(api *ostia.API) string {
+	return apicastName(api) + "-config"
+}
+
 func newHTTPProbe(path string, port int32, initDelay int32, timeout int32, period int32) *v1.Probe {
 	return &v1.Probe{
 		Handler: v1.Handler{
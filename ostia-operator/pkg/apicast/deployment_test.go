@@ -1,7 +1,12 @@
 package apicast
 
 import (
+	"encoding/json"
+	"github.com/3scale/ostia/ostia-operator/pkg/apicast/standalone"
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
 	"testing"
 )
 
@@ -19,10 +24,208 @@ func TestDeploymentConfig(t *testing.T) {
 			},
 		},
 	}
-	var _, err = DeploymentConfig(api)
+	var _, _, err = DeploymentConfig(api, standalone.Resources{})
 
 	if err != nil {
 		println("ERROR: ", err)
 		t.FailNow()
 	}
 }
+
+func TestDeploymentConfigSecret(t *testing.T) {
+	var api = &ostia.API{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "apis"},
+		Spec: ostia.APISpec{
+			Authentication: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				SecretRefs: []v1.LocalObjectReference{{Name: "consumers"}},
+			}},
+			RateLimits: []ostia.RateLimit{{Name: "all", Type: "FixedWindow", Limit: "100/s"}},
+			RateLimitStore: &ostia.RateLimitStore{URLSecretRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "redis"}, Key: "url",
+			}},
+			Endpoints: []ostia.Endpoint{
+				{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"},
+			},
+		},
+	}
+	resources := standalone.Resources{Secrets: map[string]*v1.Secret{
+		"consumers": {Data: map[string][]byte{"alice": []byte("secret-key-a")}},
+		"redis":     {Data: map[string][]byte{"url": []byte("redis://:redis-password@redis.example.com:6379/1")}},
+	}}
+
+	deployment, secret, err := DeploymentConfig(api, resources)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	if secret.Name != "apicast-shop-config" || secret.Namespace != "apis" || len(secret.OwnerReferences) != 1 {
+		t.Errorf("unexpected secret metadata %#v", secret.ObjectMeta)
+	}
+	if !strings.Contains(string(secret.Data["config.json"]), "secret-key-a") {
+		t.Errorf("api keys missing from the configuration secret")
+	}
+	if !strings.Contains(string(secret.Data["config.json"]), "redis-password") {
+		t.Errorf("rate limit store url missing from the configuration secret")
+	}
+
+	pod, err := json.Marshal(deployment)
+	if err != nil {
+		t.Fatalf("error marshalling deployment - %s", err)
+	}
+	if strings.Contains(string(pod), "secret-key-a") {
+		t.Errorf("api keys must not be rendered in the deployment")
+	}
+	if strings.Contains(string(pod), "redis-password") {
+		t.Errorf("the rate limit store url must not be rendered in the deployment")
+	}
+
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if env := container.Env[2]; env.Name != "APICAST_CONFIGURATION" || env.Value != "file:///etc/ostia/config/config.json" {
+		t.Errorf("unexpected configuration env %#v", env)
+	}
+	if volumes := deployment.Spec.Template.Spec.Volumes; len(volumes) != 1 || volumes[0].Secret.SecretName != "apicast-shop-config" {
+		t.Errorf("unexpected volumes %#v", volumes)
+	}
+	if mounts := container.VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/etc/ostia/config" || !mounts[0].ReadOnly {
+		t.Errorf("unexpected volume mounts %#v", mounts)
+	}
+
+	// Changing the keys rolls out new pods, as APIcast only reads the configuration on start
+	checksum := deployment.Spec.Template.Annotations[configChecksumAnnotation]
+	resources.Secrets["consumers"].Data["bob"] = []byte("secret-key-b")
+	if deployment, _, err = DeploymentConfig(api, resources); err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	if checksum == "" || deployment.Spec.Template.Annotations[configChecksumAnnotation] == checksum {
+		t.Errorf("expected the config checksum to change, was %q", checksum)
+	}
+}
+
+func TestDeploymentConfigEndpointVolumes(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Endpoints: []ostia.Endpoint{
+				{Name: "public", Host: "https://echo-api.3scale.net", Path: "/public"},
+				{
+					Name: "orders",
+					Host: "https://orders.internal",
+					Path: "/orders",
+					TLS: &ostia.UpstreamTLS{
+						CABundleRef:          &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "private-ca"}, Key: "bundle.pem"},
+						ClientCertificateRef: &v1.LocalObjectReference{Name: "gateway-client"},
+					},
+				},
+			},
+		},
+	}
+	resources := standalone.Resources{
+		Secrets: map[string]*v1.Secret{
+			"gateway-client": {
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway-client", ResourceVersion: "7"},
+				Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+			},
+		},
+		ConfigMaps: map[string]*v1.ConfigMap{
+			"private-ca": {
+				ObjectMeta: metav1.ObjectMeta{Name: "private-ca", ResourceVersion: "9"},
+				Data:       map[string]string{"bundle.pem": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"},
+			},
+		},
+	}
+	deployment, _, err := DeploymentConfig(api, resources)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	volumes := deployment.Spec.Template.Spec.Volumes
+	if len(volumes) != 2 || volumes[0].Secret.SecretName != "gateway-client" {
+		t.Errorf("unexpected volumes %#v", volumes)
+	}
+
+	mounts := deployment.Spec.Template.Spec.Containers[0].VolumeMounts
+	if len(mounts) != 2 || mounts[0].MountPath != "/etc/ostia/endpoints/orders/client" {
+		t.Errorf("unexpected volume mounts %#v", mounts)
+	}
+
+	// APIcast only reads the certificates on start, so their versions are in the pods to roll them out
+	annotations := deployment.Spec.Template.Annotations
+	if annotations["ostia.3scale.net/endpoint-1-client-version"] != "7" || annotations["ostia.3scale.net/endpoint-1-ca-version"] != "9" {
+		t.Errorf("missing tls version annotations %#v", annotations)
+	}
+	if _, ok := annotations["ostia.3scale.net/endpoint-0-client-version"]; ok {
+		t.Errorf("unexpected annotation of an endpoint without tls %#v", annotations)
+	}
+
+	// Both are fetched and watched, so changes render the configuration again
+	if secrets := ReferencedSecrets(api); len(secrets) != 1 || secrets[0] != "gateway-client" {
+		t.Errorf("unexpected referenced secrets %v", secrets)
+	}
+	if configMaps := ReferencedConfigMaps(api); len(configMaps) != 1 || configMaps[0] != "private-ca" {
+		t.Errorf("unexpected referenced configmaps %v", configMaps)
+	}
+}
+
+func TestDeploymentConfigCacheMaxTime(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Endpoints: []ostia.Endpoint{
+				{Name: "products", Host: "https://products.internal", Path: "/products", Caching: &ostia.Caching{TTLSeconds: 300}},
+			},
+		},
+	}
+	deployment, _, err := DeploymentConfig(api, standalone.Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	env := deployment.Spec.Template.Spec.Containers[0].Env
+	if last := env[len(env)-1]; last.Name != "APICAST_CACHE_MAX_TIME" || last.Value != "300s" {
+		t.Errorf("unexpected env %#v", env)
+	}
+
+	// The cache storage is set up by APIcast itself, no volume is needed
+	if volumes := deployment.Spec.Template.Spec.Volumes; len(volumes) != 1 {
+		t.Errorf("unexpected volumes %#v", volumes)
+	}
+}
+
+func TestGatewayTLS(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Expose:   true,
+			Hostname: "example.com",
+			TLS:      &ostia.GatewayTLS{SecretName: "example-com-tls", Listener: true},
+			Endpoints: []ostia.Endpoint{
+				{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"},
+			},
+		},
+	}
+	resources := standalone.Resources{Secrets: map[string]*v1.Secret{
+		"example-com-tls": {ObjectMeta: metav1.ObjectMeta{Name: "example-com-tls", ResourceVersion: "42"}},
+	}}
+
+	deployment, _, err := DeploymentConfig(api, resources)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	pod := deployment.Spec.Template
+	if pod.Annotations[tlsSecretVersionAnnotation] != "42" {
+		t.Errorf("missing secret version annotation %#v", pod.Annotations)
+	}
+	if ports := pod.Spec.Containers[0].Ports; len(ports) != 3 || ports[2].ContainerPort != 8443 {
+		t.Errorf("unexpected container ports %#v", ports)
+	}
+	if volumes := pod.Spec.Volumes; len(volumes) != 2 || volumes[1].Secret.SecretName != "example-com-tls" {
+		t.Errorf("unexpected volumes %#v", volumes)
+	}
+
+	if ports := Service(api).Spec.Ports; len(ports) != 3 || ports[2].Name != "https" {
+		t.Errorf("unexpected service ports %#v", ports)
+	}
+
+	tls := Ingress(api).Spec.TLS
+	if len(tls) != 1 || tls[0].SecretName != "example-com-tls" || tls[0].Hosts[0] != "example.com" {
+		t.Errorf("unexpected ingress tls %#v", tls)
+	}
+}
@@ -0,0 +1,100 @@
+package apicast
+
+import (
+	"os"
+
+	"github.com/3scale/ostia/ostia-operator/pkg/apicast/standalone"
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const defaultRedisImage = "docker.io/library/redis:5"
+
+// isRateLimitStoreManaged tells if the operator deploys the Redis keeping the rate limit counters of the API
+func isRateLimitStoreManaged(api *ostia.API) bool {
+	return api.Spec.RateLimitStore != nil && api.Spec.RateLimitStore.Managed
+}
+
+func labelsForRateLimitStore(api *ostia.API) map[string]string {
+	return map[string]string{"app": "redis", "apiRef": api.Name, "deployment": standalone.RateLimitStoreName(api)}
+}
+
+// RateLimitStoreDeployment returns the Deployment of the Redis keeping the rate limit counters of the API.
+// There is a single replica without persistence, the counters only matter for the current windows.
+func RateLimitStoreDeployment(api *ostia.API) *appsv1.Deployment {
+	name := standalone.RateLimitStoreName(api)
+	labels := labelsForRateLimitStore(api)
+	replicas := int32(1)
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: api.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Image:          getRedisImage(),
+							Name:           "redis",
+							Args:           []string{"--save", "", "--appendonly", "no"},
+							Ports:          []v1.ContainerPort{{ContainerPort: standalone.RateLimitStorePort, Name: "redis", Protocol: "TCP"}},
+							LivenessProbe:  newTCPProbe(standalone.RateLimitStorePort, 10, 5, 10),
+							ReadinessProbe: newTCPProbe(standalone.RateLimitStorePort, 5, 5, 10),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	addOwnerRefToObject(deployment, asOwner(api))
+	return deployment
+}
+
+// RateLimitStoreService returns the Service APIcast reaches the managed Redis of the API through
+func RateLimitStoreService(api *ostia.API) *v1.Service {
+	name := standalone.RateLimitStoreName(api)
+	labels := labelsForRateLimitStore(api)
+
+	service := &v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: api.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{
+				{Name: "redis", Port: standalone.RateLimitStorePort, Protocol: "TCP", TargetPort: intstr.FromInt(standalone.RateLimitStorePort)},
+			},
+			Selector: labels,
+		},
+	}
+
+	addOwnerRefToObject(service, asOwner(api))
+	return service
+}
+
+func getRedisImage() string {
+	if image, ok := os.LookupEnv("REDIS_IMAGE"); ok && image != "" {
+		return image
+	}
+	return defaultRedisImage
+}
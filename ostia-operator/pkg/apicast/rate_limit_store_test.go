@@ -0,0 +1,37 @@
+package apicast
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	api := &ostia.API{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "apis"},
+		Spec:       ostia.APISpec{RateLimitStore: &ostia.RateLimitStore{Managed: true}},
+	}
+
+	if !isRateLimitStoreManaged(api) {
+		t.Error("expected the rate limit store to be managed")
+	}
+
+	deployment := RateLimitStoreDeployment(api)
+	service := RateLimitStoreService(api)
+
+	if deployment.Name != "apicast-shop-redis" || deployment.Namespace != "apis" || len(deployment.OwnerReferences) != 1 {
+		t.Errorf("unexpected deployment metadata %#v", deployment.ObjectMeta)
+	}
+	if *deployment.Spec.Replicas != 1 || deployment.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort != 6379 {
+		t.Errorf("unexpected deployment spec %#v", deployment.Spec)
+	}
+	if service.Name != deployment.Name || service.Spec.Selector["deployment"] != deployment.Name || service.Spec.Ports[0].Port != 6379 {
+		t.Errorf("unexpected service %#v", service)
+	}
+
+	api.Spec.RateLimitStore = &ostia.RateLimitStore{}
+	if isRateLimitStoreManaged(api) {
+		t.Error("expected the rate limit store not to be managed")
+	}
+}
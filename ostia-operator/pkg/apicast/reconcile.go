@@ -3,9 +3,14 @@ package apicast
 import (
 	"context"
 	ostiav1alpha1 "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -31,56 +36,102 @@ func Reconcile(client client.Client, request reconcile.Request) (err error) {
 	}
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 
-	if api.Generation != api.Status.ObservedGeneration {
-		status := ostia.APIStatus{}
-		status.Deployed = false
-		status.ObservedGeneration = api.Generation
-		status.Conditions = []ostia.APICondition{
-			{Type: "Ready", Status: "false"},
-		}
+	// Failures of the API server are kept apart from the errors of the API spec, they are retried
+	var failures []reconcileFailure
+	var configErr error
 
-		if err = client.Status().Update(context.TODO(), api); err != nil {
-			return err
+	resources, err := fetchResources(client, api)
+	if err != nil {
+		failures = append(failures, reconcileFailure{reasonFetchReferencesFailed, err})
+	} else {
+		// Render the APIcast configuration, errors here come from the API spec and are surfaced in the status
+		var desiredDc *appsv1.Deployment
+		var desiredConfig *corev1.Secret
+		desiredDc, desiredConfig, configErr = DeploymentConfig(api, resources)
+		if configErr != nil {
+			reqLogger.Error(configErr, "Invalid API configuration")
+		} else if err = reconcileConfigSecret(client, desiredConfig); err != nil {
+			failures = append(failures, reconcileFailure{"ConfigSecretReconcileFailed", err})
+		} else if err = reconcileDeploymentConfig(client, desiredDc); err != nil {
+			failures = append(failures, reconcileFailure{"DeploymentReconcileFailed", err})
 		}
 	}
 
-	// Reconcile DeploymentConfig object
-	err = reconcileDeploymentConfig(client, api)
-	if err != nil {
-		reqLogger.Error(err, "Failed to reconcile Deployment")
+	// Reconcile Service object
+	if err = reconcileService(client, api); err != nil {
+		failures = append(failures, reconcileFailure{"ServiceReconcileFailed", err})
 	}
 
-	// Reconcile Service object
-	err = reconcileService(client, api)
-	if err != nil {
-		log.Error(err, "Failed to reconcile Service")
+	// Reconcile the Redis keeping the rate limit counters
+	if err = reconcileRateLimitStore(client, api); err != nil {
+		failures = append(failures, reconcileFailure{"RateLimitStoreReconcileFailed", err})
 	}
 
 	// Reconcile Route object
 	if api.Spec.Expose {
-		err = reconcileIngress(client, api)
-		if err != nil {
-			log.Error(err, "Failed to reconcile Ingress")
+		if err = reconcileIngress(client, api); err != nil {
+			failures = append(failures, reconcileFailure{"IngressReconcileFailed", err})
 		}
 	}
 
-	err = updateStatus(client, api)
+	var errs []error
+	for _, failure := range failures {
+		reqLogger.Error(failure.err, "Failed to reconcile API", "Reason", failure.reason)
+		errs = append(errs, failure.err)
+	}
 
-	if err != nil {
-		log.Error(err, "Failed to update API Status")
+	if err = updateStatus(client, api, configErr, failures); err != nil {
+		reqLogger.Error(err, "Failed to update API Status")
+		errs = append(errs, err)
 	}
 
-	return err
+	// Returning the failures requeues the API
+	return utilerrors.NewAggregate(errs)
 }
 
-func updateStatus(client client.Client, api *ostia.API) (err error) {
+func updateStatus(client client.Client, api *ostia.API, configErr error, failures []reconcileFailure) (err error) {
+	now := v1.Now()
 	expectedStatus := *api.Status.DeepCopy()
-	expectedStatus.Deployed = true
 	expectedStatus.ObservedGeneration = api.Generation
-	expectedStatus.Conditions = []ostia.APICondition{
-		{Type: "Ready", Status: "true"},
+
+	setCondition(&expectedStatus, configValidCondition(configErr, failures), now)
+
+	deployment := &appsv1.Deployment{}
+	if err = client.Get(context.TODO(), types.NamespacedName{Name: apicastName(api), Namespace: api.Namespace}, deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		deployment = nil
+	}
+	setCondition(&expectedStatus, deploymentAvailableCondition(deployment, api.Generation), now)
+
+	if api.Spec.Expose {
+		ingress := &extensions.Ingress{}
+		if err = client.Get(context.TODO(), types.NamespacedName{Name: apicastName(api), Namespace: api.Namespace}, ingress); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			ingress = nil
+		}
+		setCondition(&expectedStatus, ingressAdmittedCondition(ingress), now)
+	} else {
+		removeCondition(&expectedStatus, ostia.ConditionIngressAdmitted)
 	}
 
+	if len(ReferencedBackends(api)) > 0 {
+		watchNamespace, _ := k8sutil.GetWatchNamespace()
+		services, err := fetchBackends(client, api, watchNamespace)
+		if err != nil {
+			return err
+		}
+		setCondition(&expectedStatus, backendsResolvedCondition(api, services, watchNamespace), now)
+	} else {
+		removeCondition(&expectedStatus, ostia.ConditionBackendsResolved)
+	}
+
+	setCondition(&expectedStatus, readyCondition(expectedStatus, api.Spec.Expose, failures), now)
+	expectedStatus.Deployed = isConditionTrue(expectedStatus, ostia.ConditionDeploymentAvailable)
+
 	if !reflect.DeepEqual(expectedStatus, api.Status) {
 		log.Info("API Status does not match", "Expected", expectedStatus, "Actual", api.Status)
 
@@ -103,27 +154,39 @@ func namespacedName(meta v1.Object) types.NamespacedName {
 	}
 }
 
-func reconcileDeploymentConfig(client client.Client, api *ostia.API) (err error) {
-	existingDc, err := DeploymentConfig(api)
+// reconcileConfigSecret stores the configuration before the Deployment mounting it is rolled out
+func reconcileConfigSecret(client client.Client, desiredSecret *corev1.Secret) (err error) {
+	existingSecret := &corev1.Secret{}
 
+	err = client.Get(context.TODO(), namespacedName(desiredSecret), existingSecret)
 	if err != nil {
-		log.Error(err, "Failed to reconcile Deployment")
-		return err
+		err = client.Create(context.TODO(), desiredSecret)
+		log.Info("Creating configuration Secret", "Error", err)
+	} else if !reflect.DeepEqual(existingSecret.Data, desiredSecret.Data) {
+		existingSecret.Data = desiredSecret.Data
+		err = client.Update(context.TODO(), existingSecret)
+		log.Info("Updating configuration Secret", "Error", err)
 	}
 
-	desiredDc, err := DeploymentConfig(api)
-	if err != nil {
-		return err
-	}
+	return err
+}
+
+func reconcileDeploymentConfig(client client.Client, desiredDc *appsv1.Deployment) (err error) {
+	existingDc := &appsv1.Deployment{}
 
-	err = client.Get(context.TODO(), namespacedName(existingDc), existingDc)
+	err = client.Get(context.TODO(), namespacedName(desiredDc), existingDc)
 
 	if err != nil {
 		err = client.Create(context.TODO(), desiredDc)
 		log.Info("Creating Deployment", "Error", err)
 	} else {
-		if !reflect.DeepEqual(existingDc.Spec, desiredDc.Spec) {
+		if !reflect.DeepEqual(existingDc.Spec, desiredDc.Spec) ||
+			existingDc.Annotations[generationAnnotation] != desiredDc.Annotations[generationAnnotation] {
 			existingDc.Spec = desiredDc.Spec
+			if existingDc.Annotations == nil {
+				existingDc.Annotations = make(map[string]string)
+			}
+			existingDc.Annotations[generationAnnotation] = desiredDc.Annotations[generationAnnotation]
 			err = client.Update(context.TODO(), existingDc)
 			log.Info("Updating Deployment", "Error", err)
 		}
@@ -151,6 +214,59 @@ func reconcileService(client client.Client, api *ostia.API) (err error) {
 
 }
 
+// reconcileRateLimitStore deploys the managed Redis of the API, or removes it when no longer managed
+func reconcileRateLimitStore(client client.Client, api *ostia.API) (err error) {
+	desiredDeployment := RateLimitStoreDeployment(api)
+	desiredSvc := RateLimitStoreService(api)
+	existingDeployment := &appsv1.Deployment{}
+	existingSvc := &corev1.Service{}
+
+	if !isRateLimitStoreManaged(api) {
+		if err = client.Get(context.TODO(), namespacedName(desiredDeployment), existingDeployment); err == nil {
+			err = client.Delete(context.TODO(), existingDeployment)
+			log.Info("Deleting rate limit store Deployment", "Error", err)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		if err = client.Get(context.TODO(), namespacedName(desiredSvc), existingSvc); err == nil {
+			err = client.Delete(context.TODO(), existingSvc)
+			log.Info("Deleting rate limit store Service", "Error", err)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	err = client.Get(context.TODO(), namespacedName(desiredDeployment), existingDeployment)
+	if err != nil {
+		err = client.Create(context.TODO(), desiredDeployment)
+		log.Info("Creating rate limit store Deployment", "Error", err)
+	} else if !reflect.DeepEqual(existingDeployment.Spec.Template.Spec.Containers, desiredDeployment.Spec.Template.Spec.Containers) {
+		existingDeployment.Spec = desiredDeployment.Spec
+		err = client.Update(context.TODO(), existingDeployment)
+		log.Info("Updating rate limit store Deployment", "Error", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = client.Get(context.TODO(), namespacedName(desiredSvc), existingSvc)
+	if err != nil {
+		err = client.Create(context.TODO(), desiredSvc)
+		log.Info("Creating rate limit store Service", "Error", err)
+	} else if !reflect.DeepEqual(existingSvc.Spec.Ports, desiredSvc.Spec.Ports) {
+		existingSvc.Spec.Ports = desiredSvc.Spec.Ports
+		err = client.Update(context.TODO(), existingSvc)
+		log.Info("Updating rate limit store Service", "Error", err)
+	}
+
+	return err
+}
+
 func reconcileIngress(client client.Client, api *ostia.API) (err error) {
 	existingIngress := Ingress(api)
 	desiredIngress := Ingress(api)
@@ -0,0 +1,114 @@
+package apicast
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// failingClient makes the API server fail to get or create the objects of the same type as failGet or failCreate
+type failingClient struct {
+	client.Client
+	failGet    runtime.Object
+	failCreate runtime.Object
+}
+
+func (c *failingClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if c.failGet != nil && reflect.TypeOf(obj) == reflect.TypeOf(c.failGet) {
+		return errors.New("connection refused")
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *failingClient) Create(ctx context.Context, obj runtime.Object) error {
+	if c.failCreate != nil && reflect.TypeOf(obj) == reflect.TypeOf(c.failCreate) {
+		return errors.New("connection refused")
+	}
+	return c.Client.Create(ctx, obj)
+}
+
+func TestReconcileFailures(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	if err := ostia.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	inputs := []struct {
+		client            func(client.Client) client.Client
+		expectReady       string
+		expectConfigValid v1.ConditionStatus
+	}{
+		{
+			client: func(cl client.Client) client.Client {
+				return &failingClient{Client: cl, failCreate: &v1.Service{}}
+			},
+			expectReady:       "ServiceReconcileFailed",
+			expectConfigValid: v1.ConditionTrue,
+		},
+		{
+			client: func(cl client.Client) client.Client {
+				return &failingClient{Client: cl, failCreate: &v1.Secret{}}
+			},
+			expectReady:       "ConfigSecretReconcileFailed",
+			expectConfigValid: v1.ConditionTrue,
+		},
+		{
+			// The referenced Secret can't be read, so the configuration is neither valid nor invalid
+			client: func(cl client.Client) client.Client {
+				return &failingClient{Client: cl, failGet: &v1.Secret{}}
+			},
+			expectReady:       reasonFetchReferencesFailed,
+			expectConfigValid: v1.ConditionUnknown,
+		},
+	}
+
+	for _, input := range inputs {
+		api := &ostia.API{
+			ObjectMeta: metav1.ObjectMeta{Name: "hello", Namespace: "test"},
+			Spec: ostia.APISpec{
+				Endpoints: []ostia.Endpoint{{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"}},
+				Authentication: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+					SecretRefs: []v1.LocalObjectReference{{Name: "keys"}},
+				}},
+			},
+		}
+		keys := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "test"},
+			Data:       map[string][]byte{"consumer": []byte("secret")},
+		}
+		cl := input.client(fake.NewFakeClientWithScheme(s, api, keys))
+
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "hello", Namespace: "test"}}
+		if err := Reconcile(cl, request); err == nil {
+			t.Errorf("expected the failure to be returned so the api is requeued - %s", input.expectReady)
+		}
+
+		reconciled := &ostia.API{}
+		if err := cl.Get(context.TODO(), request.NamespacedName, reconciled); err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		ready := getCondition(reconciled.Status, ostia.ConditionReady)
+		if ready == nil || ready.Status != v1.ConditionFalse || ready.Reason != input.expectReady {
+			t.Errorf("expected Ready to be False with reason %s - %#v", input.expectReady, ready)
+		}
+		configValid := getCondition(reconciled.Status, ostia.ConditionConfigValid)
+		if configValid == nil || configValid.Status != input.expectConfigValid {
+			t.Errorf("expected ConfigValid to be %s - %#v", input.expectConfigValid, configValid)
+		}
+	}
+}
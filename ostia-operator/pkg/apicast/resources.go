@@ -0,0 +1,113 @@
+package apicast
+
+import (
+	"context"
+
+	"github.com/3scale/ostia/ostia-operator/pkg/apicast/standalone"
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReferencedSecrets returns the names of the Secrets, in the API namespace, used by the API
+func ReferencedSecrets(api *ostia.API) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if tls := api.Spec.TLS; tls != nil && tls.Listener {
+		add(tls.SecretName)
+	}
+
+	if store := api.Spec.RateLimitStore; store != nil && store.URLSecretRef != nil {
+		add(store.URLSecretRef.Name)
+	}
+
+	for _, endpoint := range api.Spec.Endpoints {
+		if tls := endpoint.TLS; tls != nil && tls.ClientCertificateRef != nil {
+			add(tls.ClientCertificateRef.Name)
+		}
+	}
+
+	authentications := []*ostia.Authentication{api.Spec.Authentication}
+	for _, endpoint := range api.Spec.Endpoints {
+		authentications = append(authentications, endpoint.Authentication)
+	}
+
+	for _, auth := range authentications {
+		if auth != nil && auth.APIKey != nil {
+			for _, ref := range auth.APIKey.SecretRefs {
+				add(ref.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// ReferencedConfigMaps returns the names of the ConfigMaps, in the API namespace, whose data is rendered in the configuration
+func ReferencedConfigMaps(api *ostia.API) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, endpoint := range api.Spec.Endpoints {
+		if tls := endpoint.TLS; tls != nil && tls.CABundleRef != nil {
+			add(tls.CABundleRef.Name)
+		}
+	}
+
+	return names
+}
+
+func fetchResources(client client.Client, api *ostia.API) (standalone.Resources, error) {
+	resources := standalone.Resources{
+		Secrets:    make(map[string]*v1.Secret),
+		ConfigMaps: make(map[string]*v1.ConfigMap),
+	}
+
+	for _, name := range ReferencedSecrets(api) {
+		secret := &v1.Secret{}
+		key := types.NamespacedName{Name: name, Namespace: api.Namespace}
+
+		if err := client.Get(context.TODO(), key, secret); err != nil {
+			// A missing Secret is an error of the API spec, reported when rendering the configuration
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, "Failed to get referenced Secret", "Secret", name)
+			return resources, err
+		}
+		resources.Secrets[name] = secret
+	}
+
+	for _, name := range ReferencedConfigMaps(api) {
+		configMap := &v1.ConfigMap{}
+		key := types.NamespacedName{Name: name, Namespace: api.Namespace}
+
+		if err := client.Get(context.TODO(), key, configMap); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, "Failed to get referenced ConfigMap", "ConfigMap", name)
+			return resources, err
+		}
+		resources.ConfigMaps[name] = configMap
+	}
+
+	return resources, nil
+}
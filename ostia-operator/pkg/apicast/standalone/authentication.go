@@ -0,0 +1,248 @@
+package standalone
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const (
+	conditionalPolicyName        = "apicast.policy.conditional"
+	echoPolicyName               = "apicast.policy.echo"
+	oidcAuthenticationPolicyName = "apicast.policy.oidc_authentication"
+	jwtClaimCheckPolicyName      = "apicast.policy.jwt_claim_check"
+)
+
+func processAuthentication(auth *ostia.Authentication, resources Resources) ([]Policy, error) {
+	var policies []Policy
+
+	if auth == nil {
+		return policies, nil
+	}
+
+	if auth.APIKey != nil {
+		policy, err := toAPIKeyCheck(auth.APIKey, resources)
+		if err != nil {
+			return policies, err
+		}
+		policies = append(policies, policy)
+	}
+
+	if auth.JWT != nil {
+		jwtPolicies, err := toJWTValidation(auth.JWT)
+		if err != nil {
+			return policies, err
+		}
+		policies = append(policies, jwtPolicies...)
+	}
+
+	return policies, nil
+}
+
+// toAPIKeyCheck rejects with 401 every request whose key differs from all the valid ones
+func toAPIKeyCheck(apiKey *ostia.APIKeyAuthentication, resources Resources) (Policy, error) {
+	var policy Policy
+
+	source, err := apiKeySource(apiKey)
+	if err != nil {
+		return policy, err
+	}
+
+	keys, err := apiKeys(apiKey, resources)
+	if err != nil {
+		return policy, err
+	}
+
+	var operations []Operation
+	for _, key := range keys {
+		operations = append(operations, Operation{
+			Left:      source,
+			LeftType:  "liquid",
+			Op:        "!=",
+			Right:     key,
+			RightType: "plain",
+		})
+	}
+
+	policy.Name = conditionalPolicyName
+	policy.Configuration = ConditionalPolicyConfiguration{
+		Condition: PolicyCondition{Operations: operations, CombineOp: "and"},
+		PolicyChain: []NestedPolicy{
+			{Name: echoPolicyName, Configuration: EchoPolicyConfiguration{Status: 401, Exit: "request"}},
+		},
+	}
+
+	return policy, nil
+}
+
+func apiKeySource(apiKey *ostia.APIKeyAuthentication) (string, error) {
+	name := apiKey.Name
+	if name == "" {
+		name = ostia.DefaultAPIKeyName
+	}
+
+	switch apiKey.In {
+	case "", ostia.DefaultAPIKeyLocation:
+		if !headerName.MatchString(name) {
+			return "", fmt.Errorf("invalid api key header name %q", name)
+		}
+		return fmt.Sprintf("{{headers['%s']}}", name), nil
+	case "query":
+		if !queryParamName.MatchString(name) {
+			return "", fmt.Errorf("invalid api key query param name %q, only letters, digits and _ are allowed", name)
+		}
+		return fmt.Sprintf("{{ngx.var.arg_%s}}", name), nil
+	default:
+		return "", fmt.Errorf("unknown api key location %s, must be header or query", apiKey.In)
+	}
+}
+
+// apiKeys returns the sorted values of all the referenced Secrets so the rendered config is stable
+func apiKeys(apiKey *ostia.APIKeyAuthentication, resources Resources) ([]string, error) {
+	var keys []string
+
+	if len(apiKey.SecretRefs) == 0 {
+		return keys, fmt.Errorf("api key authentication requires at least one secret")
+	}
+
+	for _, ref := range apiKey.SecretRefs {
+		secret, ok := resources.Secrets[ref.Name]
+		if !ok {
+			return keys, fmt.Errorf("secret %s referenced by api key authentication not found", ref.Name)
+		}
+		for _, value := range secret.Data {
+			if key := strings.TrimSpace(string(value)); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return keys, fmt.Errorf("no api keys found in the referenced secrets")
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// toJWTValidation returns the policy verifying the token with the keys published by the issuer,
+// followed by the one checking its claims when the token must hold some
+func toJWTValidation(jwt *ostia.JWTAuthentication) ([]Policy, error) {
+	var policies []Policy
+
+	if jwt.Issuer == "" {
+		return policies, fmt.Errorf("required property 'issuer' missing from jwt authentication")
+	}
+	if err := checkIssuer(jwt.Issuer); err != nil {
+		return policies, fmt.Errorf("invalid issuer %s - %s", jwt.Issuer, err)
+	}
+	if jwt.PublicKeyRef != nil {
+		return policies, fmt.Errorf("static public keys are not supported, tokens are verified with the keys of issuer %s", jwt.Issuer)
+	}
+	if jwt.JWKSCacheSeconds < 0 {
+		return policies, fmt.Errorf("negative 'jwksCacheSeconds' for issuer %s", jwt.Issuer)
+	}
+
+	policies = append(policies, Policy{
+		Name: oidcAuthenticationPolicyName,
+		Configuration: OIDCAuthenticationPolicyConfiguration{
+			IssuerEndpoint: jwt.Issuer,
+			Required:       true,
+			TTL:            jwt.JWKSCacheSeconds,
+		},
+	})
+
+	var rules []JWTClaimCheckRule
+
+	// The token must be issued for any of the audiences
+	if len(jwt.Audiences) > 0 {
+		var operations []JWTClaimOperation
+		for _, audience := range jwt.Audiences {
+			if err := checkAudience(audience); err != nil {
+				return policies, fmt.Errorf("invalid audience %q for issuer %s - %s", audience, jwt.Issuer, err)
+			}
+			operations = append(operations, audienceMatches(audience))
+		}
+		rules = append(rules, claimCheckRule(operations, "or"))
+	}
+
+	// and hold every claim
+	if len(jwt.Claims) > 0 {
+		var operations []JWTClaimOperation
+		for _, name := range sortedClaims(jwt.Claims) {
+			if name == "" {
+				return policies, fmt.Errorf("empty claim name for issuer %s", jwt.Issuer)
+			}
+			operations = append(operations, claimEquals(name, jwt.Claims[name]))
+		}
+		rules = append(rules, claimCheckRule(operations, "and"))
+	}
+
+	if len(rules) > 0 {
+		policies = append(policies, Policy{
+			Name:          jwtClaimCheckPolicyName,
+			Configuration: JWTClaimCheckPolicyConfiguration{Rules: rules},
+		})
+	}
+
+	return policies, nil
+}
+
+// checkIssuer verifies the issuer is an absolute URL the gateway can discover the OpenID Connect configuration from
+func checkIssuer(issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http or https url")
+	}
+	return nil
+}
+
+// sortedClaims returns the claim names sorted so the rendered config is stable
+func sortedClaims(claims map[string]string) []string {
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// claimCheckRule applies the operations to every request of the service
+func claimCheckRule(operations []JWTClaimOperation, combineOp string) JWTClaimCheckRule {
+	return JWTClaimCheckRule{
+		Resource:     "/",
+		ResourceType: "plain",
+		Methods:      []string{"ANY"},
+		Operations:   operations,
+		CombineOp:    combineOp,
+	}
+}
+
+// audienceMatches checks the aud claim holds the audience, either alone or as an item of a list.
+// The list is joined with spaces, which audiences can't contain.
+func audienceMatches(audience string) JWTClaimOperation {
+	return JWTClaimOperation{
+		Op:           "matches",
+		JWTClaim:     "{{ aud | join: ' ' }}",
+		JWTClaimType: "liquid",
+		Value:        "(^| )" + regexp.QuoteMeta(audience) + "( |$)",
+		ValueType:    "plain",
+	}
+}
+
+// checkAudience verifies the audience can be told apart from the others in the joined aud claim
+func checkAudience(audience string) error {
+	if audience == "" || strings.ContainsAny(audience, " \t\r\n") {
+		return fmt.Errorf("audience can't be empty or contain whitespace")
+	}
+	return nil
+}
+
+func claimEquals(claim string, value string) JWTClaimOperation {
+	return JWTClaimOperation{Op: "==", JWTClaim: claim, JWTClaimType: "plain", Value: value, ValueType: "plain"}
+}
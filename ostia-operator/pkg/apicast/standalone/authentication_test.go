@@ -0,0 +1,159 @@
+package standalone
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+)
+
+func TestProcessAuthentication(t *testing.T) {
+	resources := Resources{Secrets: map[string]*v1.Secret{
+		"consumers": {Data: map[string][]byte{"alice": []byte("key-a\n"), "bob": []byte("key-b")}},
+		"empty":     {Data: map[string][]byte{}},
+	}}
+
+	inputs := []struct {
+		auth          *ostia.Authentication
+		expectErr     bool
+		shouldContain string
+	}{
+		{
+			auth: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				SecretRefs: []v1.LocalObjectReference{{Name: "consumers"}},
+			}},
+			shouldContain: `{"policy":"apicast.policy.conditional","configuration":{"condition":{"operations":[` +
+				`{"left":"{{headers['X-API-Key']}}","left_type":"liquid","op":"!=","right":"key-a","right_type":"plain"},` +
+				`{"left":"{{headers['X-API-Key']}}","left_type":"liquid","op":"!=","right":"key-b","right_type":"plain"}],"combine_op":"and"},` +
+				`"policy_chain":[{"name":"apicast.policy.echo","configuration":{"status":401,"exit":"request"}}]}}`,
+		},
+		{
+			auth: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				In: "query", Name: "user_key", SecretRefs: []v1.LocalObjectReference{{Name: "consumers"}},
+			}},
+			shouldContain: `"left":"{{ngx.var.arg_user_key}}"`,
+		},
+		{
+			auth: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				In: "cookie", SecretRefs: []v1.LocalObjectReference{{Name: "consumers"}},
+			}},
+			expectErr: true,
+		},
+		{
+			auth: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				SecretRefs: []v1.LocalObjectReference{{Name: "missing"}},
+			}},
+			expectErr: true,
+		},
+		{
+			auth: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{
+				SecretRefs: []v1.LocalObjectReference{{Name: "empty"}},
+			}},
+			expectErr: true,
+		},
+		{
+			auth:      &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{}},
+			expectErr: true,
+		},
+		{
+			auth: &ostia.Authentication{JWT: &ostia.JWTAuthentication{
+				Issuer:    "https://sso.example.com/auth/realms/partners",
+				Audiences: []string{"orders", "carts"},
+				Claims:    map[string]string{"tenant": "acme", "azp": "portal"},
+			}},
+			shouldContain: `{"policy":"apicast.policy.oidc_authentication","configuration":{` +
+				`"issuer_endpoint":"https://sso.example.com/auth/realms/partners","required":true}},` +
+				`{"policy":"apicast.policy.jwt_claim_check","configuration":{"rules":[` +
+				`{"resource":"/","resource_type":"plain","methods":["ANY"],"operations":[` +
+				`{"op":"matches","jwt_claim":"{{ aud | join: ' ' }}","jwt_claim_type":"liquid","value":"(^| )orders( |$)","value_type":"plain"},` +
+				`{"op":"matches","jwt_claim":"{{ aud | join: ' ' }}","jwt_claim_type":"liquid","value":"(^| )carts( |$)","value_type":"plain"}],"combine_op":"or"},` +
+				`{"resource":"/","resource_type":"plain","methods":["ANY"],"operations":[` +
+				`{"op":"==","jwt_claim":"azp","jwt_claim_type":"plain","value":"portal","value_type":"plain"},` +
+				`{"op":"==","jwt_claim":"tenant","jwt_claim_type":"plain","value":"acme","value_type":"plain"}],"combine_op":"and"}]}}`,
+		},
+		{
+			auth:          &ostia.Authentication{JWT: &ostia.JWTAuthentication{Issuer: "https://sso.example.com"}},
+			shouldContain: `[{"policy":"apicast.policy.oidc_authentication","configuration":{"issuer_endpoint":"https://sso.example.com","required":true}}]`,
+		},
+		{
+			auth: &ostia.Authentication{JWT: &ostia.JWTAuthentication{Issuer: "https://sso.example.com", JWKSCacheSeconds: 300}},
+			shouldContain: `[{"policy":"apicast.policy.oidc_authentication","configuration":` +
+				`{"issuer_endpoint":"https://sso.example.com","required":true,"ttl":300}}]`,
+		},
+		{
+			auth:      &ostia.Authentication{JWT: &ostia.JWTAuthentication{Issuer: "sso.example.com"}},
+			expectErr: true,
+		},
+		{
+			auth: &ostia.Authentication{JWT: &ostia.JWTAuthentication{
+				Issuer:       "https://sso.example.com",
+				PublicKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "keys"}, Key: "tls.crt"},
+			}},
+			expectErr: true,
+		},
+		{
+			auth:      &ostia.Authentication{JWT: &ostia.JWTAuthentication{Issuer: "https://sso.example.com", Audiences: []string{"two words"}}},
+			expectErr: true,
+		},
+		{
+			auth:      &ostia.Authentication{JWT: &ostia.JWTAuthentication{Claims: map[string]string{"tenant": "acme"}}},
+			expectErr: true,
+		},
+	}
+
+	for _, input := range inputs {
+		policies, err := processAuthentication(input.auth, resources)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.auth)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		for _, policy := range policies {
+			if _, ok := policySchemas[policy.Name]; ok {
+				checkPolicySchema(t, policy)
+			}
+		}
+
+		conf, err := json.Marshal(policies)
+		if err != nil {
+			t.Fatalf("error marshalling policies - %s", err)
+		}
+
+		if !strings.Contains(string(conf), input.shouldContain) {
+			t.Errorf("unexpected or missing config - \nshould contain - %s \nequals -%s",
+				input.shouldContain, string(conf))
+		}
+	}
+}
+
+func TestAudienceMatches(t *testing.T) {
+	operation := audienceMatches("api.example.com")
+	audience := regexp.MustCompile(operation.Value)
+
+	// The aud claim as rendered by the liquid template, lists are joined with spaces
+	inputs := []struct {
+		aud    string
+		expect bool
+	}{
+		{aud: "api.example.com", expect: true},
+		{aud: "portal api.example.com", expect: true},
+		{aud: "api.example.com portal", expect: true},
+		{aud: "apiXexample.com", expect: false},
+		{aud: "api.example.com.evil", expect: false},
+		{aud: "my-api.example.com", expect: false},
+		{aud: "", expect: false},
+	}
+
+	for _, input := range inputs {
+		if audience.MatchString(input.aud) != input.expect {
+			t.Errorf("audience match of %q - expected %t", input.aud, input.expect)
+		}
+	}
+}
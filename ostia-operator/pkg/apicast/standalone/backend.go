@@ -0,0 +1,30 @@
+package standalone
+
+import (
+	"fmt"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+// BackendNamespace returns the namespace of the Service referenced by an Endpoint of the API
+func BackendNamespace(api *ostia.API, ref *ostia.BackendRef) string {
+	if ref.Namespace == "" {
+		return api.Namespace
+	}
+	return ref.Namespace
+}
+
+// endpointUpstream returns the URL of the server proxied by the Endpoint
+func endpointUpstream(api *ostia.API, endpoint ostia.Endpoint) string {
+	ref := endpoint.BackendRef
+	if ref == nil {
+		return endpoint.Host
+	}
+
+	scheme := ref.Scheme
+	if scheme == "" {
+		scheme = ostia.DefaultServiceScheme
+	}
+
+	return fmt.Sprintf("%s://%s.%s.svc:%d", scheme, ref.Name, BackendNamespace(api, ref), ref.Port)
+}
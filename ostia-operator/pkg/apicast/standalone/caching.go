@@ -0,0 +1,97 @@
+package standalone
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const contentCachingPolicyName = "apicast.policy.content_caching"
+
+// CacheMaxTime returns the value of APICAST_CACHE_MAX_TIME for the API, empty to keep the APIcast default
+func CacheMaxTime(api *ostia.API) string {
+	ttl, err := cacheTTL(api)
+	if err != nil || ttl <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%ds", ttl)
+}
+
+// cacheTTL returns the TTL of the Endpoints caching their responses. APIcast has a single one for all of
+// them, so the Endpoints setting it must agree.
+func cacheTTL(api *ostia.API) (int32, error) {
+	var ttl int32
+
+	for _, endpoint := range api.Spec.Endpoints {
+		if endpoint.Caching == nil || endpoint.Caching.TTLSeconds == 0 {
+			continue
+		}
+		if ttl != 0 && endpoint.Caching.TTLSeconds != ttl {
+			return 0, fmt.Errorf("cache ttl %ds of endpoint %s differs from the %ds of the other endpoints", endpoint.Caching.TTLSeconds, endpoint.Name, ttl)
+		}
+		ttl = endpoint.Caching.TTLSeconds
+	}
+
+	return ttl, nil
+}
+
+// processCaching returns the policy caching the responses to the read requests of the Endpoint
+func processCaching(endpoint ostia.Endpoint) (Policy, error) {
+	var policy Policy
+	caching := endpoint.Caching
+
+	if !isCacheable(endpoint) {
+		return policy, fmt.Errorf("'caching' requires endpoint %s to serve GET or HEAD requests", endpoint.Name)
+	}
+
+	if caching.TTLSeconds < 0 {
+		return policy, fmt.Errorf("negative 'ttlSeconds' on endpoint %s", endpoint.Name)
+	}
+	if caching.Key != nil {
+		return policy, fmt.Errorf("cache keys are not supported by APIcast on endpoint %s", endpoint.Name)
+	}
+	if caching.MaxSize != nil {
+		return policy, fmt.Errorf("cache sizes are not supported by APIcast on endpoint %s", endpoint.Name)
+	}
+
+	header := caching.Header
+	if header == "" {
+		header = ostia.DefaultCacheHeader
+	} else if !headerName.MatchString(header) {
+		return policy, fmt.Errorf("invalid cache header %q on endpoint %s", header, endpoint.Name)
+	}
+
+	var operations []Operation
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		operations = append(operations, Operation{Left: "{{http_method}}", LeftType: "liquid", Op: "==", Right: method, RightType: "plain"})
+	}
+
+	policy.Name = contentCachingPolicyName
+	policy.Configuration = ContentCachingPolicyConfiguration{
+		Rules: []ContentCachingRule{{
+			Cache:     true,
+			Header:    header,
+			Condition: PolicyCondition{Operations: operations, CombineOp: "or"},
+		}},
+	}
+
+	return policy, nil
+}
+
+// isCacheable tells if some of the methods served by the Endpoint get cached responses
+func isCacheable(endpoint ostia.Endpoint) bool {
+	if len(endpoint.Methods) == 0 {
+		return true
+	}
+
+	for _, method := range endpoint.Methods {
+		switch strings.ToUpper(method) {
+		case http.MethodGet, http.MethodHead:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessCaching(t *testing.T) {
+	readRequests := PolicyCondition{CombineOp: "or", Operations: []Operation{
+		{Left: "{{http_method}}", LeftType: "liquid", Op: "==", Right: "GET", RightType: "plain"},
+		{Left: "{{http_method}}", LeftType: "liquid", Op: "==", Right: "HEAD", RightType: "plain"},
+	}}
+
+	inputs := []struct {
+		endpoint  ostia.Endpoint
+		expectErr bool
+		expect    ContentCachingPolicyConfiguration
+	}{
+		{
+			endpoint: ostia.Endpoint{Name: "products", Caching: &ostia.Caching{}},
+			expect: ContentCachingPolicyConfiguration{Rules: []ContentCachingRule{
+				{Cache: true, Header: "X-Cache-Status", Condition: readRequests},
+			}},
+		},
+		{
+			endpoint: ostia.Endpoint{Name: "products", Methods: []string{"get", "post"}, Caching: &ostia.Caching{Header: "X-Cache"}},
+			expect: ContentCachingPolicyConfiguration{Rules: []ContentCachingRule{
+				{Cache: true, Header: "X-Cache", Condition: readRequests},
+			}},
+		},
+		{endpoint: ostia.Endpoint{Name: "products", Methods: []string{"POST"}, Caching: &ostia.Caching{}}, expectErr: true},
+		{endpoint: ostia.Endpoint{Name: "products", Caching: &ostia.Caching{Header: "bad header"}}, expectErr: true},
+		{endpoint: ostia.Endpoint{Name: "products", Caching: &ostia.Caching{Key: &ostia.CacheKey{IgnoreQuery: true}}}, expectErr: true},
+	}
+
+	for _, input := range inputs {
+		policy, err := processCaching(input.endpoint)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.endpoint.Caching)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error - %s", err)
+			continue
+		}
+		equals(t, contentCachingPolicyName, policy.Name)
+		equals(t, input.expect, policy.Configuration)
+		checkPolicySchema(t, policy)
+	}
+}
+
+func TestCacheMaxTime(t *testing.T) {
+	api := func(ttls ...int32) *ostia.API {
+		api := &ostia.API{}
+		for _, ttl := range ttls {
+			api.Spec.Endpoints = append(api.Spec.Endpoints, ostia.Endpoint{Name: "products", Caching: &ostia.Caching{TTLSeconds: ttl}})
+		}
+		return api
+	}
+
+	equals(t, "", CacheMaxTime(api()))
+	equals(t, "", CacheMaxTime(api(0)))
+	equals(t, "300s", CacheMaxTime(api(0, 300, 300)))
+
+	if _, err := cacheTTL(api(60, 300)); err == nil {
+		t.Error("expected error for endpoints with different ttls")
+	}
+}
@@ -2,67 +2,294 @@ package standalone
 
 import (
 	"encoding/json"
+	"fmt"
+	"path"
+
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
 var log = logf.Log.WithName("apicast-standalone")
 
-func serviceValues(services map[string]Service) []Service {
-	s := make([]Service, 0, len(services))
+const (
+	// HTTPSPort is where APIcast serves HTTPS when the GatewayTLS listener is enabled
+	HTTPSPort = 8443
+	// GatewayTLSDir is where the GatewayTLS Secret is mounted in the APIcast container
+	GatewayTLSDir = "/etc/ostia/tls"
+
+	httpsListenerName = "https"
+)
+
+// Resources holds the Kubernetes objects referenced by an API, indexed by name
+type Resources struct {
+	Secrets    map[string]*v1.Secret
+	ConfigMaps map[string]*v1.ConfigMap
+}
+
+func secretValue(ref *v1.SecretKeySelector, resources Resources) (string, error) {
+	secret, ok := resources.Secrets[ref.Name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", ref.Name)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s", ref.Key, ref.Name)
+	}
+
+	return string(value), nil
+}
+
+func configMapValue(ref *v1.ConfigMapKeySelector, resources Resources) (string, error) {
+	configMap, ok := resources.ConfigMaps[ref.Name]
+	if !ok {
+		return "", fmt.Errorf("configmap %s not found", ref.Name)
+	}
+
+	value, ok := configMap.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in configmap %s", ref.Key, ref.Name)
+	}
+
+	return value, nil
+}
+
+// httpsListener serves the certificate mounted from the GatewayTLS Secret
+func httpsListener() Listen {
+	return Listen{
+		Port:     HTTPSPort,
+		Name:     httpsListenerName,
+		Protocol: "http",
+		TLS: &TLS{
+			Protocols:      "TLSv1.2 TLSv1.3",
+			Certificate:    path.Join(GatewayTLSDir, "tls.crt"),
+			CertificateKey: path.Join(GatewayTLSDir, "tls.key"),
+		},
+	}
+}
+
+// withServerPort returns a copy of the routes, and the routes nested in them, matching the listener named port
+func withServerPort(routes []Route, port string) []Route {
+	copies := make([]Route, 0, len(routes))
+
+	for _, route := range routes {
+		route.Match.ServerPort = port
+		if route.Routes != nil {
+			route.Routes = withServerPort(route.Routes, port)
+		}
+		copies = append(copies, route)
+	}
+
+	return copies
+}
+
+// endpointAuthentication returns the authentication of the Endpoint, the one of the API when it has none
+func endpointAuthentication(api *ostia.API, endpoint ostia.Endpoint) *ostia.Authentication {
+	if endpoint.Authentication != nil {
+		return endpoint.Authentication
+	}
+	return api.Spec.Authentication
+}
+
+// endpointPolicies returns the policies of the Endpoint which go before rate limiting
+func endpointPolicies(endpoint ostia.Endpoint, authentication []Policy, resources Resources) ([]Policy, error) {
+	if endpoint.Authentication == nil {
+		return append([]Policy{}, authentication...), nil
+	}
+
+	return processAuthentication(endpoint.Authentication, resources)
+}
+
+// policyChain returns the policies of the service proxying to the Endpoint, in the order APIcast runs them.
+// The Endpoint inherits the settings of the API it doesn't define, see each setting for how they are combined.
+func policyChain(api *ostia.API, endpoint ostia.Endpoint, authentication []Policy, resources Resources) ([]Policy, error) {
+	var chain []Policy
+
+	custom, err := processPolicies(api.Spec.Policies, endpoint.Policies)
+	if err != nil {
+		return chain, err
+	}
+
+	ipFilter := api.Spec.IPFilter
+	if endpoint.IPFilter != nil {
+		ipFilter = endpoint.IPFilter
+	}
+	ipPolicies, err := processIPFilter(ipFilter)
+	if err != nil {
+		return chain, err
+	}
+
+	corsPolicies, err := processCORS(mergeCORS(api.Spec.CORS, endpoint.CORS))
+	if err != nil {
+		return chain, err
+	}
+
+	authPolicies, err := endpointPolicies(endpoint, authentication, resources)
+	if err != nil {
+		return chain, err
+	}
+
+	payload, err := processPayload(endpoint)
+	if err != nil {
+		return chain, err
+	}
+
+	storeURL, err := rateLimitStoreURL(api, resources)
+	if err != nil {
+		return chain, err
+	}
+
+	limits, err := endpointRateLimits(api, endpoint)
+	if err != nil {
+		return chain, err
+	}
+
+	rateLimit, err := processScopedRateLimitPolicies(limits, endpointAuthentication(api, endpoint),
+		rateLimitSettings{storeURL: storeURL, response: api.Spec.RateLimitResponse})
+	if err != nil {
+		return chain, err
+	}
+
+	headers, err := processHeaders(api.Spec.Headers, endpoint.Headers)
+	if err != nil {
+		return chain, err
+	}
+
+	chain = append(chain, custom[ostia.PolicyPositionFirst]...)
+	// Rejected addresses don't count against the rate limits
+	chain = append(chain, ipPolicies...)
+	// Preflight requests are answered before authentication
+	chain = append(chain, corsPolicies...)
+	chain = append(chain, authPolicies...)
+	// Rejected payloads don't count against the rate limits
+	chain = append(chain, payload...)
+	chain = append(chain, custom[ostia.PolicyPositionBeforeRateLimit]...)
+	chain = append(chain, rateLimit)
+	chain = append(chain, headers...)
+
+	// Responses served from the cache still count against the rate limits
+	if endpoint.Caching != nil {
+		policy, err := processCaching(endpoint)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, policy)
+	}
+
+	if endpoint.Rewrite != nil {
+		policy, err := processRewrite(endpoint)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, policy)
+	}
+
+	if endpoint.TLS != nil {
+		policy, err := processUpstreamTLS(endpoint, resources)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, policy)
+	}
 
-	for _, value := range services {
-		s = append(s, value)
+	if endpoint.CircuitBreaker != nil {
+		return chain, fmt.Errorf("circuit breakers are not supported by APIcast on endpoint %s", endpoint.Name)
 	}
 
-	return s
+	if endpoint.Timeouts != nil {
+		policy, err := processTimeouts(endpoint)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, policy)
+	}
+
+	if endpoint.Retries != 0 {
+		policy, err := processRetries(endpoint)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, policy)
+	}
+
+	return append(chain, custom[ostia.PolicyPositionLast]...), nil
 }
 
 //createConfig returns an APIcast Configuration Object
-func CreateConfig(api *ostia.API) ([]byte, error) {
+func CreateConfig(api *ostia.API, resources Resources) ([]byte, error) {
 	var standalone = NewConfiguration()
-	var rateLimit, err = processRateLimitPolicies(api.Spec.RateLimits)
 	var routes = []Route{
 		{
 			Name:        "management",
 			Match:       Match{ServerPort: "management"},
 			Destination: Destination{Service: "management"}},
 	}
-	var services = make(map[string]Service)
+	var destinations []string
+	var routedEndpoints []ostia.Endpoint
+	var services []Service
+
+	authentication, err := processAuthentication(api.Spec.Authentication, resources)
+	if err != nil {
+		log.Error(err, "Failed to configure authentication")
+		return nil, err
+	}
+
+	// The cache TTL is set in the APIcast env, it can't differ among Endpoints
+	if _, err := cacheTTL(api); err != nil {
+		log.Error(err, "Failed to configure caching")
+		return nil, err
+	}
 
 	for _, v := range api.Spec.Endpoints {
+		policies, err := policyChain(api, v, authentication, resources)
+		if err != nil {
+			log.Error(err, "Failed to configure endpoint", "Endpoint", v.Name)
+			return nil, err
+		}
+
+		// Every Endpoint gets its own service, even when sharing the host with others,
+		// so its policy chain only holds its own settings
 		var service = Service{
-			Name:        v.Host,
-			PolicyChain: []Policy{rateLimit},
-			Upstream:    v.Host,
+			Name:        v.Name,
+			PolicyChain: policies,
+			Upstream:    endpointUpstream(api, v),
 		}
-		routes = append(routes, Route{
-			Name: v.Name,
-			Match: Match{
-				URIPath:    v.Path,
-				ServerPort: "default",
-			},
-			Destination: Destination{Service: service.Name},
-		})
-		services[service.Name] = service
+
+		if mergeCORS(api.Spec.CORS, v.CORS) != nil {
+			v = withPreflight(v)
+		}
+
+		destinations = append(destinations, service.Name)
+		routedEndpoints = append(routedEndpoints, v)
+		services = append(services, service)
 	}
 
-	standalone.Routes = routes
 	standalone.Services = append(
-		serviceValues(services),
+		services,
 		Service{
 			Name: "management", PolicyChain: []Policy{
 				{Name: "apicast.policy.management"},
 			},
 		})
 
+	var apiRoutes = endpointRoutes(routedEndpoints, destinations)
+	routes = append(routes, apiRoutes...)
+
 	if api.Spec.Expose {
 		standalone.Server.Listen = []Listen{
 			{Port: 8080, Name: "default", Protocol: "http"},
 			{Port: 8090, Name: "management", Protocol: "http"},
 		}
+
+		if tls := api.Spec.TLS; tls != nil && tls.Listener {
+			standalone.Server.Listen = append(standalone.Server.Listen, httpsListener())
+			routes = append(routes, withServerPort(apiRoutes, httpsListenerName)...)
+		}
 	}
 
+	standalone.Routes = routes
+
 	b, err := json.Marshal(standalone)
 
 	if err != nil {
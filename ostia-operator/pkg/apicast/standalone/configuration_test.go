@@ -1,7 +1,9 @@
 package standalone
 
 import (
+	"encoding/json"
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 )
 
@@ -27,7 +29,7 @@ func TestCreateConfig(t *testing.T) {
 			},
 		},
 	}
-	var standalone, err = CreateConfig(api)
+	var standalone, err = CreateConfig(api, Resources{})
 
 	if err != nil {
 		println("ERROR: ", err)
@@ -36,3 +38,97 @@ func TestCreateConfig(t *testing.T) {
 		println("SUCCESS: ", standalone)
 	}
 }
+
+func TestCreateConfigTLSListener(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Expose:   true,
+			Hostname: "example.com",
+			TLS:      &ostia.GatewayTLS{SecretName: "example-com-tls", Listener: true},
+			Endpoints: []ostia.Endpoint{
+				{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"},
+			},
+		},
+	}
+
+	b, err := CreateConfig(api, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	var config Configuration
+	if err = json.Unmarshal(b, &config); err != nil {
+		t.Fatalf("error unmarshalling config - %s", err)
+	}
+
+	equals(t, Listen{Port: 8443, Name: "https", Protocol: "http", TLS: &TLS{
+		Protocols:      "TLSv1.2 TLSv1.3",
+		Certificate:    "/etc/ostia/tls/tls.crt",
+		CertificateKey: "/etc/ostia/tls/tls.key",
+	}}, config.Server.Listen[2])
+
+	var ports []string
+	for _, route := range config.Routes {
+		ports = append(ports, route.Match.ServerPort)
+	}
+	equals(t, []string{"management", "default", "https"}, ports)
+}
+
+func TestCreateConfigServices(t *testing.T) {
+	var api = &ostia.API{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "apis"},
+		Spec: ostia.APISpec{
+			Endpoints: []ostia.Endpoint{
+				{Name: "orders", Host: "https://shop.example.com", Path: "/orders",
+					RateLimits: []ostia.RateLimit{{Name: "orders", Type: "FixedWindow", Limit: "1/s"}}},
+				{Name: "carts", Host: "https://shop.example.com", Path: "/carts"},
+				{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"},
+			},
+			RateLimits: []ostia.RateLimit{{Name: "all", Type: "FixedWindow", Limit: "100/s", Scope: ostia.RateLimitScopeAPI}},
+		},
+	}
+
+	b, err := CreateConfig(api, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	var config struct {
+		Services []struct {
+			Name        string `json:"name"`
+			Upstream    string `json:"upstream"`
+			PolicyChain []struct {
+				Configuration RateLimitPolicyConfiguration `json:"configuration"`
+			} `json:"policy_chain"`
+		} `json:"internal"`
+	}
+	if err = json.Unmarshal(b, &config); err != nil {
+		t.Fatalf("error unmarshalling config - %s", err)
+	}
+
+	var names, upstreams []string
+	for _, service := range config.Services {
+		names = append(names, service.Name)
+		upstreams = append(upstreams, service.Upstream)
+	}
+	equals(t, []string{"orders", "carts", "hello", "management"}, names)
+	equals(t, []string{"https://shop.example.com", "https://shop.example.com", "https://echo-api.3scale.net", ""}, upstreams)
+
+	var keys []LimiterKey
+	for _, limiter := range *config.Services[0].PolicyChain[0].Configuration.FixedWindowLimiters {
+		keys = append(keys, limiter.Key)
+	}
+	equals(t, []LimiterKey{{"apis/shop|all", "plain", "global"}, {"apis/shop|orders", "plain", "service"}}, keys)
+	equals(t, 1, len(*config.Services[1].PolicyChain[0].Configuration.FixedWindowLimiters))
+}
+
+func TestEndpointUpstream(t *testing.T) {
+	api := &ostia.API{}
+	api.Namespace = "apis"
+
+	equals(t, "https://echo-api.3scale.net", endpointUpstream(api, ostia.Endpoint{Host: "https://echo-api.3scale.net"}))
+	equals(t, "http://orders.apis.svc:8080", endpointUpstream(api, ostia.Endpoint{BackendRef: &ostia.BackendRef{Name: "orders", Port: 8080}}))
+	equals(t, "https://carts.shop.svc:443", endpointUpstream(api, ostia.Endpoint{
+		BackendRef: &ostia.BackendRef{Name: "carts", Namespace: "shop", Port: 443, Scheme: "https"},
+	}))
+}
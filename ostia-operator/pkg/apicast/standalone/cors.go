@@ -0,0 +1,119 @@
+package standalone
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const corsPolicyName = "apicast.policy.cors"
+
+// mergeCORS returns the CORS settings of the API overridden by the ones set on the Endpoint
+func mergeCORS(api *ostia.CORS, endpoint *ostia.CORS) *ostia.CORS {
+	if api == nil || endpoint == nil {
+		if endpoint != nil {
+			return endpoint
+		}
+		return api
+	}
+
+	merged := *api
+
+	if endpoint.AllowOrigins != nil {
+		merged.AllowOrigins = endpoint.AllowOrigins
+	}
+	if endpoint.AllowMethods != nil {
+		merged.AllowMethods = endpoint.AllowMethods
+	}
+	if endpoint.AllowHeaders != nil {
+		merged.AllowHeaders = endpoint.AllowHeaders
+	}
+	if endpoint.AllowCredentials != nil {
+		merged.AllowCredentials = endpoint.AllowCredentials
+	}
+	if endpoint.MaxAge != nil {
+		merged.MaxAge = endpoint.MaxAge
+	}
+
+	return &merged
+}
+
+// processCORS returns the policy handling CORS, it has to go first in the chain so preflight requests
+// are answered before authentication
+func processCORS(cors *ostia.CORS) ([]Policy, error) {
+	var policies []Policy
+
+	if cors == nil {
+		return policies, nil
+	}
+
+	if len(cors.AllowOrigins) == 0 {
+		return policies, fmt.Errorf("cors requires at least one allowed origin")
+	}
+
+	config := CORSPolicyConfiguration{
+		AllowHeaders: cors.AllowHeaders,
+		MaxAge:       cors.MaxAge,
+	}
+	for _, method := range cors.AllowMethods {
+		config.AllowMethods = append(config.AllowMethods, strings.ToUpper(method))
+	}
+	if cors.AllowCredentials != nil {
+		config.AllowCredentials = *cors.AllowCredentials
+	}
+
+	for _, origin := range cors.AllowOrigins {
+		if origin == "*" {
+			if config.AllowCredentials {
+				return policies, fmt.Errorf("cors can't allow credentials for any origin")
+			}
+			config.AllowOrigin = "*"
+			return append(policies, Policy{Name: corsPolicyName, Configuration: config}), nil
+		}
+	}
+
+	if len(cors.AllowOrigins) == 1 {
+		config.AllowOrigin = cors.AllowOrigins[0]
+		return append(policies, Policy{Name: corsPolicyName, Configuration: config}), nil
+	}
+
+	// The policy only takes one origin, allow the one of the request when it is in the list
+	var operations []Operation
+	for _, origin := range cors.AllowOrigins {
+		operations = append(operations, Operation{
+			Left:      "{{headers['Origin']}}",
+			LeftType:  "liquid",
+			Op:        "==",
+			Right:     origin,
+			RightType: "plain",
+		})
+	}
+
+	return append(policies, Policy{
+		Name: conditionalPolicyName,
+		Configuration: ConditionalPolicyConfiguration{
+			Condition:   PolicyCondition{Operations: operations, CombineOp: "or"},
+			PolicyChain: []NestedPolicy{{Name: corsPolicyName, Configuration: config}},
+		},
+	}), nil
+}
+
+// withPreflight returns the endpoint also accepting OPTIONS when it is restricted to some methods,
+// so preflight requests don't get a 405
+func withPreflight(endpoint ostia.Endpoint) ostia.Endpoint {
+	if len(endpoint.Methods) == 0 {
+		return endpoint
+	}
+
+	for _, method := range endpoint.Methods {
+		if strings.ToUpper(method) == http.MethodOptions {
+			return endpoint
+		}
+	}
+
+	endpoint.Methods = append(append([]string{}, endpoint.Methods...), http.MethodOptions)
+
+	return endpoint
+}
@@ -0,0 +1,86 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestMergeCORS(t *testing.T) {
+	allow := true
+	maxAge := int32(600)
+
+	api := &ostia.CORS{AllowOrigins: []string{"https://example.com"}, AllowMethods: []string{"GET"}, MaxAge: &maxAge}
+	endpoint := &ostia.CORS{AllowMethods: []string{"GET", "POST"}, AllowCredentials: &allow}
+
+	equals(t, &ostia.CORS{
+		AllowOrigins:     []string{"https://example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowCredentials: &allow,
+		MaxAge:           &maxAge,
+	}, mergeCORS(api, endpoint))
+
+	equals(t, api, mergeCORS(api, nil))
+	equals(t, endpoint, mergeCORS(nil, endpoint))
+
+	if api.AllowMethods[0] != "GET" || len(api.AllowMethods) != 1 {
+		t.Errorf("api settings should not be modified - %#v", api)
+	}
+}
+
+func TestProcessCORS(t *testing.T) {
+	allow := true
+
+	inputs := []struct {
+		cors      *ostia.CORS
+		expectErr bool
+		expect    []Policy
+	}{
+		{cors: nil, expect: nil},
+		{
+			cors: &ostia.CORS{AllowOrigins: []string{"https://example.com"}, AllowMethods: []string{"get"}, AllowCredentials: &allow},
+			expect: []Policy{{Name: corsPolicyName, Configuration: CORSPolicyConfiguration{
+				AllowOrigin: "https://example.com", AllowMethods: []string{"GET"}, AllowCredentials: true,
+			}}},
+		},
+		{
+			cors:   &ostia.CORS{AllowOrigins: []string{"https://example.com", "*"}, AllowHeaders: []string{"Authorization"}},
+			expect: []Policy{{Name: corsPolicyName, Configuration: CORSPolicyConfiguration{AllowOrigin: "*", AllowHeaders: []string{"Authorization"}}}},
+		},
+		{
+			cors: &ostia.CORS{AllowOrigins: []string{"https://a.example.com", "https://b.example.com"}},
+			expect: []Policy{{Name: conditionalPolicyName, Configuration: ConditionalPolicyConfiguration{
+				Condition: PolicyCondition{CombineOp: "or", Operations: []Operation{
+					{Left: "{{headers['Origin']}}", LeftType: "liquid", Op: "==", Right: "https://a.example.com", RightType: "plain"},
+					{Left: "{{headers['Origin']}}", LeftType: "liquid", Op: "==", Right: "https://b.example.com", RightType: "plain"},
+				}},
+				PolicyChain: []NestedPolicy{{Name: corsPolicyName, Configuration: CORSPolicyConfiguration{}}},
+			}}},
+		},
+		{cors: &ostia.CORS{}, expectErr: true},
+		{cors: &ostia.CORS{AllowOrigins: []string{"*"}, AllowCredentials: &allow}, expectErr: true},
+	}
+
+	for _, input := range inputs {
+		policies, err := processCORS(input.cors)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.cors)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		equals(t, input.expect, policies)
+	}
+}
+
+func TestWithPreflight(t *testing.T) {
+	endpoint := ostia.Endpoint{Methods: []string{"GET"}}
+
+	equals(t, []string{"GET", "OPTIONS"}, withPreflight(endpoint).Methods)
+	equals(t, []string{"GET"}, endpoint.Methods)
+	equals(t, []string(nil), withPreflight(ostia.Endpoint{}).Methods)
+	equals(t, []string{"options"}, withPreflight(ostia.Endpoint{Methods: []string{"options"}}).Methods)
+}
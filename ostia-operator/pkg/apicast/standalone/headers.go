@@ -0,0 +1,82 @@
+package standalone
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const headersPolicyName = "apicast.policy.headers"
+
+var headerOps = []string{string(ostia.HeaderAdd), string(ostia.HeaderSet), string(ostia.HeaderRemove)}
+
+// headerName is a token as defined in RFC 7230, without the ' closing the liquid strings headers are read with
+var headerName = regexp.MustCompile("^[!#$%&*+.^_`|~0-9A-Za-z-]+$")
+
+// processHeaders returns the policy applying the rules of every Headers in order, if there is any
+func processHeaders(all ...*ostia.Headers) ([]Policy, error) {
+	var policies []Policy
+	var config HeadersPolicyConfiguration
+
+	for _, headers := range all {
+		if headers == nil {
+			continue
+		}
+
+		for _, rule := range headers.Request {
+			command, err := headerCommand(rule)
+			if err != nil {
+				return policies, err
+			}
+			config.Request = append(config.Request, command)
+		}
+
+		for _, rule := range headers.Response {
+			command, err := headerCommand(rule)
+			if err != nil {
+				return policies, err
+			}
+			config.Response = append(config.Response, command)
+		}
+	}
+
+	if len(config.Request) == 0 && len(config.Response) == 0 {
+		return policies, nil
+	}
+
+	return append(policies, Policy{Name: headersPolicyName, Configuration: config}), nil
+}
+
+func headerCommand(rule ostia.HeaderRule) (HeaderCommand, error) {
+	command := HeaderCommand{Header: rule.Name}
+
+	if !headerName.MatchString(rule.Name) {
+		return command, fmt.Errorf("invalid header name '%s'", rule.Name)
+	}
+
+	switch rule.Op {
+	case ostia.HeaderAdd:
+		command.Op = "push"
+	case ostia.HeaderSet:
+		command.Op = "set"
+	case ostia.HeaderRemove:
+		command.Op = "delete"
+		return command, nil
+	default:
+		return command, fmt.Errorf("unsupported header operation '%s'", rule.Op)
+	}
+
+	if rule.Value == "" {
+		return command, fmt.Errorf("value required to %s header %s", rule.Op, rule.Name)
+	}
+
+	command.Value = rule.Value
+	command.ValueType = "plain"
+	if strings.Contains(rule.Value, "{{") || strings.Contains(rule.Value, "{%") {
+		command.ValueType = "liquid"
+	}
+
+	return command, nil
+}
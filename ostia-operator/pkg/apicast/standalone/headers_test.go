@@ -0,0 +1,49 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessHeaders(t *testing.T) {
+	api := &ostia.Headers{
+		Request: []ostia.HeaderRule{
+			{Op: ostia.HeaderSet, Name: "X-Tenant", Value: "acme"},
+			{Op: ostia.HeaderAdd, Name: "X-Request-Id", Value: "{{ ngx.var.request_id }}"},
+		},
+		Response: []ostia.HeaderRule{{Op: ostia.HeaderRemove, Name: "X-Internal"}},
+	}
+	endpoint := &ostia.Headers{
+		Request: []ostia.HeaderRule{{Op: ostia.HeaderSet, Name: "X-Tenant", Value: "{{ headers['X-Org'] }}"}},
+	}
+
+	policies, err := processHeaders(api, nil, endpoint)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	equals(t, []Policy{{Name: headersPolicyName, Configuration: HeadersPolicyConfiguration{
+		Request: []HeaderCommand{
+			{Op: "set", Header: "X-Tenant", Value: "acme", ValueType: "plain"},
+			{Op: "push", Header: "X-Request-Id", Value: "{{ ngx.var.request_id }}", ValueType: "liquid"},
+			{Op: "set", Header: "X-Tenant", Value: "{{ headers['X-Org'] }}", ValueType: "liquid"},
+		},
+		Response: []HeaderCommand{{Op: "delete", Header: "X-Internal"}},
+	}}}, policies)
+
+	if policies, _ := processHeaders(nil, &ostia.Headers{}); len(policies) != 0 {
+		t.Errorf("expected no policy without rules - %#v", policies)
+	}
+
+	invalid := []ostia.HeaderRule{
+		{Op: "replace", Name: "X-Tenant", Value: "acme"},
+		{Op: ostia.HeaderSet, Name: "X-Tenant"},
+		{Op: ostia.HeaderRemove, Name: "X Tenant"},
+	}
+	for _, rule := range invalid {
+		if _, err := processHeaders(&ostia.Headers{Response: []ostia.HeaderRule{rule}}); err == nil {
+			t.Errorf("expected error for %#v", rule)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package standalone
+
+import (
+	"fmt"
+	"net"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const ipCheckPolicyName = "apicast.policy.ip_check"
+
+// processIPFilter returns the policies rejecting the denied addresses first and then the ones not allowed
+func processIPFilter(filter *ostia.IPFilter) ([]Policy, error) {
+	var policies []Policy
+
+	if filter == nil {
+		return policies, nil
+	}
+
+	if len(filter.Allow) == 0 && len(filter.Deny) == 0 {
+		return policies, fmt.Errorf("ip filter requires allowed or denied addresses")
+	}
+
+	sources := []string{"last_caller"}
+	if filter.TrustForwardedFor {
+		sources = []string{"X-Forwarded-For", "last_caller"}
+	}
+
+	checks := []struct {
+		ips       []string
+		checkType string
+	}{
+		{filter.Deny, "blacklist"},
+		{filter.Allow, "whitelist"},
+	}
+
+	for _, check := range checks {
+		if len(check.ips) == 0 {
+			continue
+		}
+
+		for _, ip := range check.ips {
+			if !isIPOrCIDR(ip) {
+				return policies, fmt.Errorf("invalid ip or cidr '%s'", ip)
+			}
+		}
+
+		policies = append(policies, Policy{
+			Name: ipCheckPolicyName,
+			Configuration: IPCheckPolicyConfiguration{
+				IPs:             check.ips,
+				CheckType:       check.checkType,
+				ErrorMessage:    "IP address not allowed",
+				ClientIPSources: sources,
+			},
+		})
+	}
+
+	return policies, nil
+}
+
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
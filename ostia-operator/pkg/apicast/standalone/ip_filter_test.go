@@ -0,0 +1,55 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessIPFilter(t *testing.T) {
+	inputs := []struct {
+		filter    *ostia.IPFilter
+		expectErr bool
+		expect    []Policy
+	}{
+		{filter: nil, expect: nil},
+		{
+			filter: &ostia.IPFilter{Allow: []string{"10.0.0.0/8", "fd00::/8"}, Deny: []string{"10.0.0.1"}, TrustForwardedFor: true},
+			expect: []Policy{
+				{Name: ipCheckPolicyName, Configuration: IPCheckPolicyConfiguration{
+					IPs: []string{"10.0.0.1"}, CheckType: "blacklist", ErrorMessage: "IP address not allowed",
+					ClientIPSources: []string{"X-Forwarded-For", "last_caller"},
+				}},
+				{Name: ipCheckPolicyName, Configuration: IPCheckPolicyConfiguration{
+					IPs: []string{"10.0.0.0/8", "fd00::/8"}, CheckType: "whitelist", ErrorMessage: "IP address not allowed",
+					ClientIPSources: []string{"X-Forwarded-For", "last_caller"},
+				}},
+			},
+		},
+		{
+			filter: &ostia.IPFilter{Allow: []string{"192.168.0.0/16"}},
+			expect: []Policy{
+				{Name: ipCheckPolicyName, Configuration: IPCheckPolicyConfiguration{
+					IPs: []string{"192.168.0.0/16"}, CheckType: "whitelist", ErrorMessage: "IP address not allowed",
+					ClientIPSources: []string{"last_caller"},
+				}},
+			},
+		},
+		{filter: &ostia.IPFilter{}, expectErr: true},
+		{filter: &ostia.IPFilter{Deny: []string{"10.0.0.0/33"}}, expectErr: true},
+	}
+
+	for _, input := range inputs {
+		policies, err := processIPFilter(input.filter)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.filter)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		equals(t, input.expect, policies)
+	}
+}
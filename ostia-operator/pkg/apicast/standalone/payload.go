@@ -0,0 +1,49 @@
+package standalone
+
+import (
+	"fmt"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const payloadLimitsPolicyName = "apicast.policy.payload_limits"
+
+// processPayload returns the policy rejecting the requests to the Endpoint, and the responses of its upstream,
+// with a body too large
+func processPayload(endpoint ostia.Endpoint) ([]Policy, error) {
+	var policies []Policy
+
+	if endpoint.RequestSchemaRef != nil {
+		return policies, fmt.Errorf("request schemas are not supported by APIcast on endpoint %s", endpoint.Name)
+	}
+	if endpoint.PayloadRejectStatus != 0 {
+		return policies, fmt.Errorf("payload reject statuses are not supported by APIcast on endpoint %s", endpoint.Name)
+	}
+
+	if endpoint.MaxRequestBodySize == nil && endpoint.MaxResponseBodySize == nil {
+		return policies, nil
+	}
+
+	var config PayloadLimitsPolicyConfiguration
+	var err error
+	if config.Request, err = bodySize("maxRequestBodySize", endpoint.MaxRequestBodySize); err != nil {
+		return policies, fmt.Errorf("%s on endpoint %s", err, endpoint.Name)
+	}
+	if config.Response, err = bodySize("maxResponseBodySize", endpoint.MaxResponseBodySize); err != nil {
+		return policies, fmt.Errorf("%s on endpoint %s", err, endpoint.Name)
+	}
+
+	return append(policies, Policy{Name: payloadLimitsPolicyName, Configuration: config}), nil
+}
+
+// bodySize returns the size in bytes, 0 meaning no limit
+func bodySize(name string, size *resource.Quantity) (int64, error) {
+	if size == nil {
+		return 0, nil
+	}
+	if size.Sign() <= 0 {
+		return 0, fmt.Errorf("'%s' must be greater than 0", name)
+	}
+	return size.Value(), nil
+}
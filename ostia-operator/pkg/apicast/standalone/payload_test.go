@@ -0,0 +1,56 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestProcessPayload(t *testing.T) {
+	requestSize := resource.MustParse("1Mi")
+	responseSize := resource.MustParse("10Mi")
+	zero := resource.MustParse("0")
+
+	inputs := []struct {
+		endpoint  ostia.Endpoint
+		expectErr bool
+		expect    []Policy
+	}{
+		{endpoint: ostia.Endpoint{Name: "orders"}},
+		{
+			endpoint: ostia.Endpoint{Name: "orders", MaxRequestBodySize: &requestSize},
+			expect: []Policy{
+				{Name: payloadLimitsPolicyName, Configuration: PayloadLimitsPolicyConfiguration{Request: 1024 * 1024}},
+			},
+		},
+		{
+			endpoint: ostia.Endpoint{Name: "orders", MaxRequestBodySize: &requestSize, MaxResponseBodySize: &responseSize},
+			expect: []Policy{
+				{Name: payloadLimitsPolicyName, Configuration: PayloadLimitsPolicyConfiguration{Request: 1024 * 1024, Response: 10 * 1024 * 1024}},
+			},
+		},
+		{endpoint: ostia.Endpoint{Name: "orders", MaxRequestBodySize: &zero}, expectErr: true},
+		{endpoint: ostia.Endpoint{Name: "orders", MaxRequestBodySize: &requestSize, PayloadRejectStatus: 400}, expectErr: true},
+		{endpoint: ostia.Endpoint{Name: "orders", RequestSchemaRef: &v1.ConfigMapKeySelector{Key: "schema.json"}}, expectErr: true},
+	}
+
+	for _, input := range inputs {
+		policies, err := processPayload(input.endpoint)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for endpoint %#v", input.endpoint)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error - %s", err)
+			continue
+		}
+		equals(t, input.expect, policies)
+		for _, policy := range policies {
+			checkPolicySchema(t, policy)
+		}
+	}
+}
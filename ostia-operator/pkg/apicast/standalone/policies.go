@@ -0,0 +1,100 @@
+package standalone
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+var policyPositions = []string{
+	string(ostia.PolicyPositionFirst), string(ostia.PolicyPositionBeforeRateLimit), string(ostia.PolicyPositionLast),
+}
+
+// bundledPolicies returns the names of the policies that can be added as is, sorted
+func bundledPolicies() []string {
+	names := make([]string, 0, len(policySchemas))
+	for name := range policySchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// processPolicies returns the Policies of every list, in order, indexed by their position in the chain
+func processPolicies(all ...[]ostia.Policy) (map[ostia.PolicyPosition][]Policy, error) {
+	positions := make(map[ostia.PolicyPosition][]Policy)
+
+	for _, policies := range all {
+		for _, p := range policies {
+			policy, err := toPolicy(p)
+			if err != nil {
+				return positions, err
+			}
+
+			position := p.Position
+			if position == "" {
+				position = ostia.DefaultPolicyPosition
+			}
+			positions[position] = append(positions[position], policy)
+		}
+	}
+
+	return positions, nil
+}
+
+func toPolicy(p ostia.Policy) (Policy, error) {
+	var policy Policy
+
+	switch p.Position {
+	case "", ostia.PolicyPositionFirst, ostia.PolicyPositionBeforeRateLimit, ostia.PolicyPositionLast:
+	default:
+		return policy, fmt.Errorf("unknown position '%s' for policy %s", p.Position, p.Name)
+	}
+
+	config, errs := policyConfiguration(p)
+	if len(errs) > 0 {
+		return policy, fmt.Errorf("invalid policy %s: %s", p.Name, joinErrors(errs))
+	}
+
+	policy.Name = p.Name
+	policy.Version = p.Version
+	if policy.Version == "" {
+		policy.Version = ostia.DefaultPolicyVersion
+	}
+	policy.Configuration = config
+
+	return policy, nil
+}
+
+// policyConfiguration returns the configuration of the Policy after checking it against the bundled schema
+func policyConfiguration(p ostia.Policy) (json.RawMessage, []error) {
+	var errs []error
+
+	schema, ok := policySchemas[p.Name]
+	if !ok {
+		return nil, append(errs, fmt.Errorf("unknown policy, must be one of %v", bundledPolicies()))
+	}
+
+	if p.Version != "" && p.Version != ostia.DefaultPolicyVersion {
+		return nil, append(errs, fmt.Errorf("version %s not supported, only %s policies are bundled", p.Version, ostia.DefaultPolicyVersion))
+	}
+
+	raw := json.RawMessage("{}")
+	if p.Configuration != nil && len(p.Configuration.Raw) > 0 {
+		raw = json.RawMessage(p.Configuration.Raw)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, append(errs, fmt.Errorf("configuration is not valid json - %s", err))
+	}
+
+	s, err := parseSchema(schema)
+	if err != nil {
+		return nil, append(errs, fmt.Errorf("bundled schema can't be parsed - %s", err))
+	}
+
+	return raw, s.validate(value, "configuration")
+}
@@ -0,0 +1,146 @@
+package standalone
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestProcessPolicies(t *testing.T) {
+	raw := func(config string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(config)}
+	}
+
+	api := []ostia.Policy{
+		{Name: "apicast.policy.logging", Configuration: raw(`{"enable_access_logs":false}`)},
+		{Name: "apicast.policy.maintenance_mode", Position: ostia.PolicyPositionFirst},
+	}
+	endpoint := []ostia.Policy{
+		{Name: "apicast.policy.retry", Version: "builtin", Configuration: raw(`{"retries":3}`), Position: ostia.PolicyPositionLast},
+	}
+
+	positions, err := processPolicies(api, endpoint)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	equals(t, map[ostia.PolicyPosition][]Policy{
+		ostia.PolicyPositionFirst: {
+			{Name: "apicast.policy.maintenance_mode", Version: "builtin", Configuration: json.RawMessage(`{}`)},
+		},
+		ostia.PolicyPositionLast: {
+			{Name: "apicast.policy.logging", Version: "builtin", Configuration: json.RawMessage(`{"enable_access_logs":false}`)},
+			{Name: "apicast.policy.retry", Version: "builtin", Configuration: json.RawMessage(`{"retries":3}`)},
+		},
+	}, positions)
+
+	invalid := []ostia.Policy{
+		{Name: "apicast.policy.custom"},
+		{Name: "apicast.policy.retry", Version: "1.0.0"},
+		{Name: "apicast.policy.retry", Position: "middle"},
+		{Name: "apicast.policy.retry", Configuration: raw(`{"retries":20}`)},
+		{Name: "apicast.policy.ip_check", Configuration: raw(`{"ips":["10.0.0.0/8"],"check_type":"allow"}`)},
+		{Name: "apicast.policy.headers", Configuration: raw(`{"request":[{"op":"set"}]}`)},
+	}
+	for _, policy := range invalid {
+		if _, err := processPolicies([]ostia.Policy{policy}); err == nil {
+			t.Errorf("expected error for %#v", policy)
+		}
+	}
+}
+
+func TestPolicyChainOrder(t *testing.T) {
+	api := &ostia.API{Spec: ostia.APISpec{
+		IPFilter: &ostia.IPFilter{Allow: []string{"10.0.0.0/8"}},
+		Policies: []ostia.Policy{{Name: "apicast.policy.logging", Position: ostia.PolicyPositionFirst}},
+	}}
+	endpoint := ostia.Endpoint{
+		Name:    "hello",
+		Rewrite: &ostia.Rewrite{StripPrefix: true},
+		Policies: []ostia.Policy{
+			{Name: "apicast.policy.retry", Position: ostia.PolicyPositionLast},
+			{Name: "apicast.policy.liquid_context_debug", Position: ostia.PolicyPositionBeforeRateLimit},
+		},
+	}
+
+	chain, err := policyChain(api, endpoint, nil, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	var names []string
+	for _, policy := range chain {
+		names = append(names, policy.Name)
+	}
+
+	equals(t, []string{
+		"apicast.policy.logging",
+		ipCheckPolicyName,
+		"apicast.policy.liquid_context_debug",
+		rateLimitPolicyName,
+		urlRewritingPolicyName,
+		"apicast.policy.retry",
+	}, names)
+}
+
+// checkPolicySchema fails when the configuration of a generated policy doesn't match the bundled schema of the
+// APIcast policy, or sets properties the schema doesn't define, which APIcast would silently ignore
+func checkPolicySchema(t *testing.T, policy Policy) {
+	t.Helper()
+
+	schema, ok := policySchemas[policy.Name]
+	if !ok {
+		t.Errorf("no bundled schema for policy %s", policy.Name)
+		return
+	}
+
+	s, err := parseSchema(schema)
+	if err != nil {
+		t.Fatalf("error parsing schema of %s - %s", policy.Name, err)
+	}
+
+	b, err := json.Marshal(policy.Configuration)
+	if err != nil {
+		t.Fatalf("error marshalling configuration of %s - %s", policy.Name, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		t.Fatalf("error unmarshalling configuration of %s - %s", policy.Name, err)
+	}
+
+	for _, err := range s.validate(value, "configuration") {
+		t.Errorf("invalid %s - %s", policy.Name, err)
+	}
+	for _, property := range unknownProperties(s, value, "configuration") {
+		t.Errorf("invalid %s - %s is not defined by the schema", policy.Name, property)
+	}
+}
+
+func unknownProperties(s *jsonSchema, value interface{}, path string) []string {
+	var unknown []string
+
+	switch v := value.(type) {
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				unknown = append(unknown, unknownProperties(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		if s.Properties == nil {
+			return unknown
+		}
+		for name, property := range v {
+			if schema, ok := s.Properties[name]; ok {
+				unknown = append(unknown, unknownProperties(schema, property, path+"."+name)...)
+			} else {
+				unknown = append(unknown, path+"."+name)
+			}
+		}
+	}
+
+	return unknown
+}
@@ -0,0 +1,210 @@
+package standalone
+
+// policySchemas are the configuration schemas of the builtin APIcast policies that can be added as is, no other
+// policy is allowed. They are hand written subsets of the apicast-policy.json manifests shipped with APIcast, using
+// only the keywords jsonSchema checks, so a configuration they accept can still be rejected by APIcast.
+var policySchemas = map[string]string{
+	"apicast.policy.echo": `{
+		"type": "object",
+		"properties": {
+			"status": {"type": "integer", "minimum": 100, "maximum": 599},
+			"exit": {"type": "string", "enum": ["request", "set"]}
+		}
+	}`,
+	"apicast.policy.headers": `{
+		"type": "object",
+		"properties": {
+			"request": {"type": "array", "items": {
+				"type": "object",
+				"required": ["op", "header"],
+				"properties": {
+					"op": {"type": "string", "enum": ["add", "set", "push", "delete"]},
+					"header": {"type": "string", "minLength": 1},
+					"value": {"type": "string"},
+					"value_type": {"type": "string", "enum": ["plain", "liquid"]}
+				}
+			}},
+			"response": {"type": "array", "items": {
+				"type": "object",
+				"required": ["op", "header"],
+				"properties": {
+					"op": {"type": "string", "enum": ["add", "set", "push", "delete"]},
+					"header": {"type": "string", "minLength": 1},
+					"value": {"type": "string"},
+					"value_type": {"type": "string", "enum": ["plain", "liquid"]}
+				}
+			}}
+		}
+	}`,
+	"apicast.policy.url_rewriting": `{
+		"type": "object",
+		"properties": {
+			"commands": {"type": "array", "items": {
+				"type": "object",
+				"required": ["op", "regex", "replace"],
+				"properties": {
+					"op": {"type": "string", "enum": ["sub", "gsub"]},
+					"regex": {"type": "string"},
+					"replace": {"type": "string"},
+					"options": {"type": "string"},
+					"break": {"type": "boolean"}
+				}
+			}},
+			"query_args_commands": {"type": "array", "items": {
+				"type": "object",
+				"required": ["op", "arg"],
+				"properties": {
+					"op": {"type": "string", "enum": ["add", "set", "push", "delete"]},
+					"arg": {"type": "string"},
+					"value": {"type": "string"},
+					"value_type": {"type": "string", "enum": ["plain", "liquid"]}
+				}
+			}}
+		}
+	}`,
+	"apicast.policy.cors": `{
+		"type": "object",
+		"properties": {
+			"allow_headers": {"type": "array", "items": {"type": "string"}},
+			"allow_methods": {"type": "array", "items": {"type": "string",
+				"enum": ["GET", "HEAD", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "TRACE", "CONNECT"]}},
+			"allow_origin": {"type": "string"},
+			"allow_credentials": {"type": "boolean"},
+			"max_age": {"type": "integer", "minimum": 0}
+		}
+	}`,
+	"apicast.policy.ip_check": `{
+		"type": "object",
+		"required": ["ips", "check_type"],
+		"properties": {
+			"ips": {"type": "array", "items": {"type": "string"}},
+			"check_type": {"type": "string", "enum": ["blacklist", "whitelist"]},
+			"error_msg": {"type": "string"},
+			"client_ip_sources": {"type": "array", "minItems": 1, "items": {"type": "string",
+				"enum": ["X-Real-IP", "X-Forwarded-For", "last_caller"]}}
+		}
+	}`,
+	"apicast.policy.upstream": `{
+		"type": "object",
+		"properties": {
+			"rules": {"type": "array", "items": {
+				"type": "object",
+				"required": ["regex", "url"],
+				"properties": {
+					"regex": {"type": "string"},
+					"url": {"type": "string"}
+				}
+			}}
+		}
+	}`,
+	"apicast.policy.retry": `{
+		"type": "object",
+		"properties": {
+			"retries": {"type": "integer", "minimum": 1, "maximum": 10}
+		}
+	}`,
+	"apicast.policy.maintenance_mode": `{
+		"type": "object",
+		"properties": {
+			"status": {"type": "integer", "minimum": 100, "maximum": 599},
+			"message": {"type": "string"},
+			"message_content_type": {"type": "string"}
+		}
+	}`,
+	"apicast.policy.logging": `{
+		"type": "object",
+		"properties": {
+			"enable_access_logs": {"type": "boolean"},
+			"custom_logging": {"type": "string"},
+			"enable_json_logs": {"type": "boolean"}
+		}
+	}`,
+	"apicast.policy.liquid_context_debug": `{
+		"type": "object",
+		"properties": {}
+	}`,
+	"apicast.policy.oidc_authentication": `{
+		"type": "object",
+		"properties": {
+			"issuer_endpoint": {"type": "string"},
+			"required": {"type": "boolean"},
+			"ttl": {"type": "integer"}
+		}
+	}`,
+	"apicast.policy.jwt_claim_check": `{
+		"type": "object",
+		"properties": {
+			"error_message": {"type": "string"},
+			"rules": {"type": "array", "items": {
+				"type": "object",
+				"required": ["resource", "operations"],
+				"properties": {
+					"resource": {"type": "string"},
+					"resource_type": {"type": "string", "enum": ["plain", "liquid"]},
+					"methods": {"type": "array", "items": {"type": "string"}},
+					"combine_op": {"type": "string", "enum": ["and", "or"]},
+					"operations": {"type": "array", "items": {
+						"type": "object",
+						"required": ["op", "jwt_claim", "value"],
+						"properties": {
+							"op": {"type": "string", "enum": ["==", "!=", "matches"]},
+							"jwt_claim": {"type": "string"},
+							"jwt_claim_type": {"type": "string", "enum": ["plain", "liquid"]},
+							"value": {"type": "string"},
+							"value_type": {"type": "string", "enum": ["plain", "liquid"]}
+						}
+					}}
+				}
+			}}
+		}
+	}`,
+	"apicast.policy.upstream_mtls": `{
+		"type": "object",
+		"properties": {
+			"certificate_type": {"type": "string", "enum": ["path", "embedded"]},
+			"certificate": {"type": "string"},
+			"certificate_key_type": {"type": "string", "enum": ["path", "embedded"]},
+			"certificate_key": {"type": "string"},
+			"verify": {"type": "boolean"},
+			"ca_certificates": {"type": "array", "items": {"type": "string"}}
+		}
+	}`,
+	"apicast.policy.content_caching": `{
+		"type": "object",
+		"properties": {
+			"rules": {"type": "array", "items": {
+				"type": "object",
+				"required": ["cache", "condition"],
+				"properties": {
+					"cache": {"type": "boolean"},
+					"header": {"type": "string"},
+					"condition": {
+						"type": "object",
+						"required": ["operations"],
+						"properties": {
+							"combine_op": {"type": "string", "enum": ["and", "or"]},
+							"operations": {"type": "array", "items": {
+								"type": "object",
+								"required": ["left", "op", "right"],
+								"properties": {
+									"left": {"type": "string"},
+									"left_type": {"type": "string", "enum": ["plain", "liquid"]},
+									"op": {"type": "string", "enum": ["==", "!=", "matches"]},
+									"right": {"type": "string"},
+									"right_type": {"type": "string", "enum": ["plain", "liquid"]}
+								}
+							}}
+						}
+					}
+				}
+			}}
+		}
+	}`,
+	"apicast.policy.payload_limits": `{
+		"type": "object",
+		"properties": {
+			"request": {"type": "integer", "minimum": 0},
+			"response": {"type": "integer", "minimum": 0}
+		}
+	}`,
+}
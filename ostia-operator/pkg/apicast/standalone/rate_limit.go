@@ -1,37 +1,159 @@
 package standalone
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
 )
 
-const rateLimitPolicyName = "apicast.policy.rate_limit"
+const (
+	rateLimitPolicyName = "apicast.policy.rate_limit"
+
+	// limiterScopeService counts the requests of each service apart
+	limiterScopeService = "service"
+	// limiterScopeGlobal counts the requests of every service together
+	limiterScopeGlobal = "global"
+)
+
+var rateLimitScopes = []string{
+	string(ostia.RateLimitScopeEndpoint), string(ostia.RateLimitScopeAPI), string(ostia.RateLimitScopeGlobal),
+}
+
+// scopedRateLimit is a RateLimit with the APIcast scope of its counters
+type scopedRateLimit struct {
+	ostia.RateLimit
+	scope string
+	// prefix keeps apart the counters of the APIs sharing a rate limit store, as the services of their
+	// Endpoints are only unique within an API
+	prefix string
+}
+
+// toScoped returns the limit with the APIcast scope of its Scope, or of the default one when not set
+func toScoped(api *ostia.API, limit ostia.RateLimit) (scopedRateLimit, error) {
+	scope := limit.Scope
+	if scope == "" {
+		scope = ostia.DefaultRateLimitScope
+	}
+
+	prefix := api.Namespace + "/" + api.Name + "|"
+
+	switch scope {
+	case ostia.RateLimitScopeEndpoint:
+		return scopedRateLimit{limit, limiterScopeService, prefix}, nil
+	case ostia.RateLimitScopeAPI:
+		return scopedRateLimit{limit, limiterScopeGlobal, prefix}, nil
+	case ostia.RateLimitScopeGlobal:
+		if !isSharedStore(api.Spec.RateLimitStore) {
+			return scopedRateLimit{}, fmt.Errorf("global rate limit %s requires a 'rateLimitStore' with an 'urlSecretRef' shared with the other APIs", limit.Name)
+		}
+		return scopedRateLimit{limit, limiterScopeGlobal, ""}, nil
+	default:
+		return scopedRateLimit{}, fmt.Errorf("unknown scope %s of rate limit %s", scope, limit.Name)
+	}
+}
+
+// isSharedStore tells if other APIs can count in the store, a managed one belongs to a single API
+func isSharedStore(store *ostia.RateLimitStore) bool {
+	return store != nil && store.URLSecretRef != nil && !store.Managed
+}
+
+// endpointRateLimits returns the rate limits enforced on the requests to the Endpoint:
+// the ones of the API, except those with the name of a rate limit of the Endpoint, which replaces them,
+// and the ones of the Endpoint
+func endpointRateLimits(api *ostia.API, endpoint ostia.Endpoint) ([]scopedRateLimit, error) {
+	var limits []scopedRateLimit
+
+	overridden := make(map[string]bool)
+	for _, limit := range endpoint.RateLimits {
+		overridden[limit.Name] = true
+	}
+
+	for _, limit := range api.Spec.RateLimits {
+		if overridden[limit.Name] {
+			continue
+		}
+		scoped, err := toScoped(api, limit)
+		if err != nil {
+			return limits, err
+		}
+		limits = append(limits, scoped)
+	}
+
+	for _, limit := range endpoint.RateLimits {
+		scoped, err := toScoped(api, limit)
+		if err != nil {
+			return limits, err
+		}
+		limits = append(limits, scoped)
+	}
+
+	return limits, nil
+}
+
+// checkLimiterKeys verifies the limits don't count in the same key of the same scope, as APIcast requires.
+// Limits whose key can't be built are skipped, processing them returns the error.
+func checkLimiterKeys(limits []scopedRateLimit, auth *ostia.Authentication) error {
+	type scopedKey struct{ name, scope string }
+	seen := make(map[scopedKey]string)
+
+	for _, limit := range limits {
+		key, err := parseLimiterKey(limit, auth)
+		if err != nil {
+			continue
+		}
+
+		k := scopedKey{key.Name, key.Scope}
+		if other, ok := seen[k]; ok {
+			return fmt.Errorf("rate limits %s and %s count in the same key %s of scope %s", other, limit.Name, key.Name, key.Scope)
+		}
+		seen[k] = limit.Name
+	}
+
+	return nil
+}
 
 func processRateLimitPolicies(limits []ostia.RateLimit) (Policy, error) {
+	scoped := make([]scopedRateLimit, 0, len(limits))
+	for _, limit := range limits {
+		scoped = append(scoped, scopedRateLimit{limit, limiterScopeService, ""})
+	}
+
+	return processScopedRateLimitPolicies(scoped, nil, rateLimitSettings{})
+}
+
+// processScopedRateLimitPolicies returns the policy enforcing the limits on the requests authenticated with auth,
+// with the store and response of the API settings
+func processScopedRateLimitPolicies(limits []scopedRateLimit, auth *ostia.Authentication, settings rateLimitSettings) (Policy, error) {
 	var policy Policy
 	var fixedLimiters []FixedWindowRateLimiter
 	var leakyLimiters []LeakyBucketRateLimiter
 	var connLimiters []ConnectionRateLimiter
 
+	if err := checkLimiterKeys(limits, auth); err != nil {
+		return policy, err
+	}
+
 	for _, limit := range limits {
 		switch limiterType := limit.Type; limiterType {
 		case "FixedWindow":
-			fw, err := toFixedWindow(limit)
+			fw, err := toFixedWindow(limit, auth)
 			if err != nil {
 				return policy, err
 			}
 			fixedLimiters = append(fixedLimiters, fw)
 		case "LeakyBucket":
-			lb, err := toLeakyBucket(limit)
+			lb, err := toLeakyBucket(limit, auth)
 			if err != nil {
 				return policy, err
 			}
 			leakyLimiters = append(leakyLimiters, lb)
 		case "ConnectionBased":
-			cb, err := toConnectionBased(limit)
+			cb, err := toConnectionBased(limit, auth)
 			if err != nil {
 				return policy, err
 			}
@@ -41,7 +163,10 @@ func processRateLimitPolicies(limits []ostia.RateLimit) (Policy, error) {
 		}
 	}
 
-	var config RateLimitPolicyConfiguration
+	config := RateLimitPolicyConfiguration{RedisURL: settings.storeURL}
+	if err := processRateLimitResponse(&config, settings.response); err != nil {
+		return policy, err
+	}
 	if len(fixedLimiters) > 0 {
 		config.FixedWindowLimiters = &fixedLimiters
 	}
@@ -58,94 +183,197 @@ func processRateLimitPolicies(limits []ostia.RateLimit) (Policy, error) {
 	return policy, nil
 }
 
-func toFixedWindow(rl ostia.RateLimit) (FixedWindowRateLimiter, error) {
-	count, window, err := parseTimeLimits(rl)
+func toFixedWindow(rl scopedRateLimit, auth *ostia.Authentication) (FixedWindowRateLimiter, error) {
+	count, window, err := parseTimeLimits(rl.RateLimit)
+	if err != nil {
+		return FixedWindowRateLimiter{}, err
+	}
+
+	key, err := parseLimiterKey(rl, auth)
+	if err != nil {
+		return FixedWindowRateLimiter{}, err
+	}
+
+	condition, err := toLimiterCondition(rl.Conditions)
 	if err != nil {
 		return FixedWindowRateLimiter{}, err
 	}
 
 	fw := FixedWindowRateLimiter{
-		Condition: rl.Conditions,
+		Condition: condition,
 		Count:     count,
-		Key:       parseLimiterKey(rl),
+		Key:       key,
 		Window:    window,
 	}
 
 	return fw, nil
 }
 
-func toLeakyBucket(rl ostia.RateLimit) (LeakyBucketRateLimiter, error) {
+func toLeakyBucket(rl scopedRateLimit, auth *ostia.Authentication) (LeakyBucketRateLimiter, error) {
 	var burst int
 
-	rate, seconds, err := parseTimeLimits(rl)
+	rate, seconds, err := parseTimeLimits(rl.RateLimit)
+	if err != nil {
+		return LeakyBucketRateLimiter{}, err
+	}
+
+	key, err := parseLimiterKey(rl, auth)
+	if err != nil {
+		return LeakyBucketRateLimiter{}, err
+	}
+
+	condition, err := toLimiterCondition(rl.Conditions)
 	if err != nil {
 		return LeakyBucketRateLimiter{}, err
 	}
 
 	if rl.Burst == nil || *rl.Burst < 0 {
-		log.Info("setting 'burst' value for %s to 0", rl.Name)
+		log.Info("setting 'burst' value to 0", "RateLimit", rl.Name)
 	} else {
 		burst = *rl.Burst
 	}
 
-	return LeakyBucketRateLimiter{burst, rl.Conditions, parseLimiterKey(rl), rate / seconds}, nil
+	// The rate is per second, so limits over longer windows leak less than one request per second
+	return LeakyBucketRateLimiter{burst, condition, key, float64(rate) / float64(seconds)}, nil
 }
 
-func toConnectionBased(rl ostia.RateLimit) (ConnectionRateLimiter, error) {
+func toConnectionBased(rl scopedRateLimit, auth *ostia.Authentication) (ConnectionRateLimiter, error) {
 	var burst, conn, delay int
 
+	key, err := parseLimiterKey(rl, auth)
+	if err != nil {
+		return ConnectionRateLimiter{}, err
+	}
+
+	condition, err := toLimiterCondition(rl.Conditions)
+	if err != nil {
+		return ConnectionRateLimiter{}, err
+	}
+
 	if rl.Conn == nil || *rl.Conn < 1 {
 		return ConnectionRateLimiter{}, fmt.Errorf("required property 'conn' not valid for rate limit %s", rl.Limit)
 	}
 	conn = *rl.Conn
 
 	if rl.Burst == nil || *rl.Burst < 0 {
-		log.Info("setting 'burst' value for %s to 0", rl.Name)
+		log.Info("setting 'burst' value to 0", "RateLimit", rl.Name)
 	} else {
 		burst = *rl.Burst
 	}
 
 	if rl.Delay == nil || *rl.Delay < 0 {
-		log.Info("setting 'delay' value for %s to 0", rl.Name)
+		log.Info("setting 'delay' value to 0", "RateLimit", rl.Name)
 	} else {
 		delay = *rl.Delay
 	}
 
-	return ConnectionRateLimiter{burst, rl.Conditions, conn, delay, parseLimiterKey(rl)}, nil
+	return ConnectionRateLimiter{burst, condition, conn, delay, key}, nil
+}
+
+// toLimiterCondition renders the conditions of a rate limit into APIcast operations
+func toLimiterCondition(conditions *ostia.Condition) (*PolicyCondition, error) {
+	if conditions == nil {
+		return nil, nil
+	}
+
+	condition := &PolicyCondition{CombineOp: conditions.Operator}
+	for _, rateLimitCondition := range conditions.Operations {
+		b, err := rateLimitCondition.MarshalAPIcast()
+		if err != nil {
+			return nil, err
+		}
+
+		var operation Operation
+		if err := json.Unmarshal(b, &operation); err != nil {
+			return nil, err
+		}
+		condition.Operations = append(condition.Operations, operation)
+	}
+
+	return condition, nil
 }
 
+// timeUnits are the seconds of the units rate limit windows are expressed in
+var timeUnits = map[string]int{
+	"s": 1, "sec": 1,
+	"m": 60, "min": 60,
+	"h": 60 * 60, "hr": 60 * 60,
+	"d": 24 * 60 * 60, "day": 24 * 60 * 60,
+}
+
+// unitWindow matches the windows made of a single unit, optionally preceded by how many of them, like 10s or day
+var unitWindow = regexp.MustCompile(`^([0-9]*)([a-z]+)$`)
+
+// parseTimeLimits returns the number of requests allowed and the window, in seconds, they are counted in.
+// Limits are written requests/window, a second when the window is missing, see parseWindow for the windows.
 func parseTimeLimits(rl ostia.RateLimit) (int, int, error) {
-	var requests, seconds int
 	if rl.Limit == "" {
-		return requests, seconds, fmt.Errorf("required property 'limit' missing from rate limit %s", rl.Limit)
+		return 0, 0, fmt.Errorf("required property 'limit' missing from rate limit %s", rl.Name)
 	}
-	seconds = 1
-	parsedLimitVal := strings.Split(rl.Limit, "/")
-	requests, err := strconv.Atoi(parsedLimitVal[0])
+
+	parts := strings.SplitN(rl.Limit, "/", 2)
+	requests, err := strconv.Atoi(parts[0])
 	if err != nil || requests < 1 {
-		return requests, seconds, fmt.Errorf("'limit' value  for %s must be a non-negative integer", rl.Limit)
+		return 0, 0, fmt.Errorf("'limit' %s of rate limit %s must start with a positive integer", rl.Limit, rl.Name)
 	}
 
-	if len(parsedLimitVal) == 2 {
-		switch parsedLimitVal[1] {
-		case "s":
-			break
-		case "m":
-			seconds = 60
-		case "hr":
-			seconds = 60 * 60
-		default:
-			fmt.Printf("unrecognised unit of time %s, for rate limit %s. defaulting to seconds", parsedLimitVal[1], rl.Limit)
+	seconds := 1
+	if len(parts) == 2 {
+		if seconds, err = parseWindow(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("%s in 'limit' %s of rate limit %s", err, rl.Limit, rl.Name)
 		}
 	}
+
 	return requests, seconds, nil
 }
 
-func parseLimiterKey(rl ostia.RateLimit) LimiterKey {
-	key := LimiterKey{rl.Name, "plain", "service"}
-	if rl.Source != "" {
+// parseWindow returns the seconds of a window written as a unit (s, m, hr, day...), a number of units (10s, 2day)
+// or a duration like 1h30m. Windows must be whole seconds, as that's what APIcast counts in.
+func parseWindow(window string) (int, error) {
+	if match := unitWindow.FindStringSubmatch(window); match != nil {
+		if unit, ok := timeUnits[match[2]]; ok {
+			count := 1
+			if match[1] != "" {
+				var err error
+				if count, err = strconv.Atoi(match[1]); err != nil || count < 1 {
+					return 0, fmt.Errorf("window %s must have a positive number of %s", window, match[2])
+				}
+			}
+			return count * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognised window %s, must be one of s, m, hr or day, optionally preceded by a number, or a duration like 1h30m", window)
+	}
+	if duration < time.Second || duration%time.Second != 0 {
+		return 0, fmt.Errorf("window %s must be a whole number of seconds", window)
+	}
+
+	return int(duration / time.Second), nil
+}
+
+// parseLimiterKey returns the key of the counters of the rate limit, see limiterKeyTemplate for the KeySources
+func parseLimiterKey(rl scopedRateLimit, auth *ostia.Authentication) (LimiterKey, error) {
+	key := LimiterKey{rl.Name, "plain", rl.scope}
+
+	switch {
+	case rl.Source != "" && len(rl.KeySources) > 0:
+		return key, fmt.Errorf("only one of 'source' and 'keySources' can be set on rate limit %s", rl.Name)
+	case len(rl.KeySources) > 0:
+		template, err := limiterKeyTemplate(rl.RateLimit, auth)
+		if err != nil {
+			return key, err
+		}
+		key.Name = template
+		key.NameType = "liquid"
+	case rl.Source != "":
 		key.Name = rl.Source
 		key.NameType = "liquid"
 	}
-	return key
+
+	key.Name = rl.prefix + key.Name
+
+	return key, nil
 }
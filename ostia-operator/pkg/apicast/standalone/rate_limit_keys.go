@@ -0,0 +1,91 @@
+package standalone
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+// queryParamName matches the query params nginx exposes as ngx.var.arg_<name>
+var queryParamName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// checkKeySource verifies exactly one field of the source is set, with a value usable in a liquid template
+func checkKeySource(source ostia.KeySource) error {
+	set := 0
+
+	if source.ClientIP {
+		set++
+	}
+	if source.Header != "" {
+		set++
+		if !headerName.MatchString(source.Header) {
+			return fmt.Errorf("invalid header name %q", source.Header)
+		}
+	}
+	if source.JWTClaim != "" {
+		set++
+		if strings.ContainsAny(source.JWTClaim, `'\{}`) {
+			return fmt.Errorf("invalid jwt claim name %q", source.JWTClaim)
+		}
+	}
+	if source.APIKey {
+		set++
+	}
+	if source.QueryParam != "" {
+		set++
+		if !queryParamName.MatchString(source.QueryParam) {
+			return fmt.Errorf("invalid query param name %q, only letters, digits and _ are allowed", source.QueryParam)
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("exactly one of clientIP, header, jwtClaim, apiKey or queryParam must be set")
+	}
+
+	return nil
+}
+
+// keySourceTemplate returns the liquid template rendering the value of the source, auth being the
+// authentication of the Endpoint the requests are for
+func keySourceTemplate(source ostia.KeySource, auth *ostia.Authentication) (string, error) {
+	if err := checkKeySource(source); err != nil {
+		return "", err
+	}
+
+	switch {
+	case source.ClientIP:
+		return "{{remote_addr}}", nil
+	case source.Header != "":
+		return fmt.Sprintf("{{headers['%s']}}", source.Header), nil
+	case source.JWTClaim != "":
+		if auth == nil || auth.JWT == nil {
+			return "", fmt.Errorf("jwt claim %s requires jwt authentication", source.JWTClaim)
+		}
+		return fmt.Sprintf("{{jwt['%s']}}", source.JWTClaim), nil
+	case source.APIKey:
+		if auth == nil || auth.APIKey == nil {
+			return "", fmt.Errorf("api key requires api key authentication")
+		}
+		return apiKeySource(auth.APIKey)
+	default:
+		return fmt.Sprintf("{{ngx.var.arg_%s}}", source.QueryParam), nil
+	}
+}
+
+// limiterKeyTemplate returns the liquid template of the counter key of the rate limit, starting with
+// its name so rate limits keyed on the same sources keep their counters apart
+func limiterKeyTemplate(rl ostia.RateLimit, auth *ostia.Authentication) (string, error) {
+	parts := []string{rl.Name}
+
+	for _, source := range rl.KeySources {
+		template, err := keySourceTemplate(source, auth)
+		if err != nil {
+			return "", fmt.Errorf("%s in the key of rate limit %s", err, rl.Name)
+		}
+		parts = append(parts, template)
+	}
+
+	return strings.Join(parts, "|"), nil
+}
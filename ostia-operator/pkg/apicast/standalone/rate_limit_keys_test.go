@@ -0,0 +1,106 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+)
+
+func TestParseLimiterKey(t *testing.T) {
+	apiKeyAuth := &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{In: "query", Name: "user_key"}}
+	jwtAuth := &ostia.Authentication{JWT: &ostia.JWTAuthentication{Issuer: "https://sso.example.com"}}
+
+	inputs := []struct {
+		limit     ostia.RateLimit
+		auth      *ostia.Authentication
+		expectErr bool
+		expect    LimiterKey
+	}{
+		{
+			limit:  ostia.RateLimit{Name: "all"},
+			expect: LimiterKey{"all", "plain", "service"},
+		},
+		{
+			limit:  ostia.RateLimit{Name: "per-ip", Source: "{{remote_addr}}"},
+			expect: LimiterKey{"{{remote_addr}}", "liquid", "service"},
+		},
+		{
+			limit:  ostia.RateLimit{Name: "per-ip", KeySources: []ostia.KeySource{{ClientIP: true}}},
+			expect: LimiterKey{"per-ip|{{remote_addr}}", "liquid", "service"},
+		},
+		{
+			limit:  ostia.RateLimit{Name: "per-tenant", KeySources: []ostia.KeySource{{Header: "X-Tenant"}, {QueryParam: "region"}}},
+			expect: LimiterKey{"per-tenant|{{headers['X-Tenant']}}|{{ngx.var.arg_region}}", "liquid", "service"},
+		},
+		{
+			limit:  ostia.RateLimit{Name: "per-user", KeySources: []ostia.KeySource{{JWTClaim: "sub"}}},
+			auth:   jwtAuth,
+			expect: LimiterKey{"per-user|{{jwt['sub']}}", "liquid", "service"},
+		},
+		{
+			limit:  ostia.RateLimit{Name: "per-key", KeySources: []ostia.KeySource{{APIKey: true}, {ClientIP: true}}},
+			auth:   apiKeyAuth,
+			expect: LimiterKey{"per-key|{{ngx.var.arg_user_key}}|{{remote_addr}}", "liquid", "service"},
+		},
+		{limit: ostia.RateLimit{Name: "per-user", KeySources: []ostia.KeySource{{JWTClaim: "sub"}}}, auth: apiKeyAuth, expectErr: true},
+		{limit: ostia.RateLimit{Name: "per-key", KeySources: []ostia.KeySource{{APIKey: true}}}, expectErr: true},
+		{limit: ostia.RateLimit{Name: "both", KeySources: []ostia.KeySource{{ClientIP: true, Header: "X-Tenant"}}}, expectErr: true},
+		{limit: ostia.RateLimit{Name: "none", KeySources: []ostia.KeySource{{}}}, expectErr: true},
+		{limit: ostia.RateLimit{Name: "bad", KeySources: []ostia.KeySource{{QueryParam: "user-id"}}}, expectErr: true},
+		{limit: ostia.RateLimit{Name: "bad", KeySources: []ostia.KeySource{{Header: "X-Tenant']}}"}}}, expectErr: true},
+		{
+			limit:     ostia.RateLimit{Name: "bad", KeySources: []ostia.KeySource{{APIKey: true}}},
+			auth:      &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{In: "query", Name: "user-key"}},
+			expectErr: true,
+		},
+		{limit: ostia.RateLimit{Name: "bad", KeySources: []ostia.KeySource{{JWTClaim: "x'}}{{"}}}, auth: jwtAuth, expectErr: true},
+		{
+			limit:     ostia.RateLimit{Name: "mixed", Source: "{{remote_addr}}", KeySources: []ostia.KeySource{{ClientIP: true}}},
+			expectErr: true,
+		},
+	}
+
+	for _, input := range inputs {
+		key, err := parseLimiterKey(scopedRateLimit{RateLimit: input.limit, scope: limiterScopeService}, input.auth)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for rate limit %#v", input.limit)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error - %s", err)
+			continue
+		}
+		equals(t, input.expect, key)
+	}
+}
+
+func TestPolicyChainLimiterKeyAuthentication(t *testing.T) {
+	api := &ostia.API{Spec: ostia.APISpec{
+		RateLimits: []ostia.RateLimit{
+			{Name: "per-key", Type: "FixedWindow", Limit: "10/m", KeySources: []ostia.KeySource{{APIKey: true}}, Scope: ostia.RateLimitScopeAPI},
+		},
+		Authentication: &ostia.Authentication{APIKey: &ostia.APIKeyAuthentication{Name: "X-Key"}},
+	}}
+	api.Name = "shop"
+	api.Namespace = "apis"
+	resources := Resources{Secrets: map[string]*v1.Secret{"keys": {Data: map[string][]byte{"a": []byte("secret")}}}}
+	api.Spec.Authentication.APIKey.SecretRefs = []v1.LocalObjectReference{{Name: "keys"}}
+
+	chain, err := policyChain(api, ostia.Endpoint{Name: "orders"}, nil, resources)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	limiters := *chain[0].Configuration.(RateLimitPolicyConfiguration).FixedWindowLimiters
+	equals(t, "apis/shop|per-key|{{headers['X-Key']}}", limiters[0].Key.Name)
+
+	// The Endpoint authenticates with JWT instead, so the API key can't tell the requests apart
+	endpoint := ostia.Endpoint{Name: "public", Authentication: &ostia.Authentication{JWT: &ostia.JWTAuthentication{
+		Issuer: "https://sso.example.com",
+	}}}
+	if _, err := policyChain(api, endpoint, nil, resources); err == nil {
+		t.Error("expected error keying on the api key of an endpoint without api key authentication")
+	}
+}
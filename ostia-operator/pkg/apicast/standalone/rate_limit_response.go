@@ -0,0 +1,39 @@
+package standalone
+
+import (
+	"fmt"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+// rateLimitSettings are shared by every rate limit policy of the API
+type rateLimitSettings struct {
+	storeURL string // Counts in the Redis at storeURL when set
+	response *ostia.RateLimitResponse
+}
+
+// processRateLimitResponse renders the status of the requests over a limit into the rate limit policy configuration
+func processRateLimitResponse(config *RateLimitPolicyConfiguration, response *ostia.RateLimitResponse) error {
+	if response == nil {
+		return nil
+	}
+
+	if response.Status != 0 && (response.Status < 400 || response.Status > 599) {
+		return fmt.Errorf("'status' of the rate limit response must be an http error status")
+	}
+	if response.Body != "" || response.ContentType != "" {
+		return fmt.Errorf("the body of the rate limit response is not supported by APIcast")
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = ostia.DefaultRateLimitStatus
+	}
+
+	config.LimitsExceededError = &LimitsExceededError{
+		StatusCode:    int(status),
+		ErrorHandling: "exit",
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package standalone
+
+import (
+	"encoding/json"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessRateLimitResponse(t *testing.T) {
+	inputs := []struct {
+		response *ostia.RateLimitResponse
+		expected RateLimitPolicyConfiguration
+		err      bool
+	}{
+		{
+			response: nil,
+			expected: RateLimitPolicyConfiguration{},
+		},
+		{
+			response: &ostia.RateLimitResponse{},
+			expected: RateLimitPolicyConfiguration{
+				LimitsExceededError: &LimitsExceededError{StatusCode: 429, ErrorHandling: "exit"},
+			},
+		},
+		{
+			response: &ostia.RateLimitResponse{Status: 503},
+			expected: RateLimitPolicyConfiguration{
+				LimitsExceededError: &LimitsExceededError{StatusCode: 503, ErrorHandling: "exit"},
+			},
+		},
+		{
+			response: &ostia.RateLimitResponse{Status: 200},
+			err:      true,
+		},
+		{
+			response: &ostia.RateLimitResponse{Status: 429, Body: "slow down", ContentType: "text/plain"},
+			err:      true,
+		},
+	}
+
+	for _, input := range inputs {
+		var config RateLimitPolicyConfiguration
+		err := processRateLimitResponse(&config, input.response)
+		if input.err {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.response)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+		equals(t, input.expected, config)
+	}
+}
+
+func TestRateLimitResponseConfiguration(t *testing.T) {
+	limits := []scopedRateLimit{{ostia.RateLimit{Name: "all", Type: "FixedWindow", Limit: "10/s"}, "service", ""}}
+	response := &ostia.RateLimitResponse{Status: 429}
+
+	policy, err := processScopedRateLimitPolicies(limits, nil, rateLimitSettings{response: response})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	b, err := json.Marshal(policy.Configuration)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	var config map[string]interface{}
+	if err = json.Unmarshal(b, &config); err != nil {
+		t.Fatalf("error unmarshalling config - %s", err)
+	}
+
+	equals(t, map[string]interface{}{"status_code": 429.0, "error_handling": "exit"}, config["limits_exceeded_error"])
+}
+
+func TestCreateConfigRateLimitResponse(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			RateLimits:        []ostia.RateLimit{{Name: "all", Type: "FixedWindow", Limit: "10/s"}},
+			RateLimitResponse: &ostia.RateLimitResponse{Status: 503},
+			Endpoints:         []ostia.Endpoint{{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"}},
+		},
+	}
+
+	b, err := CreateConfig(api, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	var config Configuration
+	if err = json.Unmarshal(b, &config); err != nil {
+		t.Fatalf("error unmarshalling config - %s", err)
+	}
+
+	for _, policy := range config.Services[0].PolicyChain {
+		if policy.Name != rateLimitPolicyName {
+			continue
+		}
+
+		rateLimit, ok := policy.Configuration.(map[string]interface{})
+		if !ok {
+			t.Fatalf("unexpected configuration - %#v", policy.Configuration)
+		}
+		equals(t, map[string]interface{}{"status_code": 503.0, "error_handling": "exit"}, rateLimit["limits_exceeded_error"])
+		return
+	}
+	t.Fatalf("missing %s - %#v", rateLimitPolicyName, config.Services[0].PolicyChain)
+}
@@ -0,0 +1,50 @@
+package standalone
+
+import (
+	"fmt"
+	"net/url"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+// RateLimitStorePort is where the managed Redis of an API listens
+const RateLimitStorePort = 6379
+
+// RateLimitStoreName returns the name of the Deployment and Service of the managed Redis of the API
+func RateLimitStoreName(api *ostia.API) string {
+	return "apicast-" + api.Name + "-redis"
+}
+
+// rateLimitStoreURL returns the URL of the Redis shared by the replicas, empty when each replica counts apart
+func rateLimitStoreURL(api *ostia.API, resources Resources) (string, error) {
+	store := api.Spec.RateLimitStore
+
+	switch {
+	case store == nil:
+		return "", nil
+	case store.Managed && store.URLSecretRef != nil:
+		return "", fmt.Errorf("only one of 'urlSecretRef' and 'managed' can be set on the rate limit store")
+	case store.Managed:
+		return fmt.Sprintf("redis://%s:%d/0", RateLimitStoreName(api), RateLimitStorePort), nil
+	case store.URLSecretRef != nil:
+		value, err := secretValue(store.URLSecretRef, resources)
+		if err != nil {
+			return "", err
+		}
+		if err := checkRedisURL(value); err != nil {
+			return "", fmt.Errorf("%s in secret %s", err, store.URLSecretRef.Name)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("one of 'urlSecretRef' or 'managed' is required on the rate limit store")
+	}
+}
+
+// checkRedisURL doesn't return the URL in the error as it may hold a password
+func checkRedisURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return fmt.Errorf("rate limit store url must be a redis:// or rediss:// url")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+)
+
+func TestRateLimitStoreURL(t *testing.T) {
+	resources := Resources{Secrets: map[string]*v1.Secret{
+		"redis": {Data: map[string][]byte{
+			"url":   []byte("redis://:secret@redis.example.com:6379/1"),
+			"plain": []byte("redis.example.com:6379"),
+		}},
+	}}
+	secretRef := func(key string) *v1.SecretKeySelector {
+		return &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "redis"}, Key: key}
+	}
+
+	inputs := []struct {
+		store     *ostia.RateLimitStore
+		expectErr bool
+		expect    string
+	}{
+		{store: nil, expect: ""},
+		{store: &ostia.RateLimitStore{Managed: true}, expect: "redis://apicast-shop-redis:6379/0"},
+		{store: &ostia.RateLimitStore{URLSecretRef: secretRef("url")}, expect: "redis://:secret@redis.example.com:6379/1"},
+		{store: &ostia.RateLimitStore{URLSecretRef: secretRef("plain")}, expectErr: true},
+		{store: &ostia.RateLimitStore{URLSecretRef: secretRef("missing")}, expectErr: true},
+		{store: &ostia.RateLimitStore{URLSecretRef: secretRef("url"), Managed: true}, expectErr: true},
+		{store: &ostia.RateLimitStore{}, expectErr: true},
+	}
+
+	for _, input := range inputs {
+		api := &ostia.API{Spec: ostia.APISpec{RateLimitStore: input.store}}
+		api.Name = "shop"
+
+		url, err := rateLimitStoreURL(api, resources)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.store)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error - %s", err)
+			continue
+		}
+		equals(t, input.expect, url)
+	}
+}
+
+func TestPolicyChainRateLimitStore(t *testing.T) {
+	api := &ostia.API{Spec: ostia.APISpec{
+		RateLimits:     []ostia.RateLimit{{Name: "all", Type: "FixedWindow", Limit: "100/s"}},
+		RateLimitStore: &ostia.RateLimitStore{Managed: true},
+	}}
+	api.Name = "shop"
+
+	chain, err := policyChain(api, ostia.Endpoint{Name: "orders"}, nil, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	equals(t, "redis://apicast-shop-redis:6379/0", chain[0].Configuration.(RateLimitPolicyConfiguration).RedisURL)
+}
+
+func TestPolicyChainSharedStore(t *testing.T) {
+	resources := Resources{Secrets: map[string]*v1.Secret{
+		"redis": {Data: map[string][]byte{"url": []byte("redis://redis.example.com:6379/1")}},
+	}}
+	store := &ostia.RateLimitStore{URLSecretRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "redis"}, Key: "url"}}
+
+	// Both APIs have an Endpoint named orders, which names its service, and count in the same store
+	keys := make(map[LimiterKey]string)
+	for _, name := range []string{"shop", "outlet"} {
+		api := &ostia.API{Spec: ostia.APISpec{
+			RateLimits: []ostia.RateLimit{
+				{Name: "all", Type: "FixedWindow", Limit: "100/s"},
+				{Name: "per-client", Type: "FixedWindow", Limit: "10/s", KeySources: []ostia.KeySource{{ClientIP: true}}, Scope: ostia.RateLimitScopeAPI},
+				{Name: "partners", Type: "FixedWindow", Limit: "1000/m", Scope: ostia.RateLimitScopeGlobal},
+			},
+			RateLimitStore: store,
+		}}
+		api.Name = name
+		api.Namespace = "apis"
+
+		chain, err := policyChain(api, ostia.Endpoint{Name: "orders"}, nil, resources)
+		if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+		for _, limiter := range *chain[0].Configuration.(RateLimitPolicyConfiguration).FixedWindowLimiters {
+			if other, ok := keys[limiter.Key]; ok {
+				t.Errorf("APIs %s and %s count in the same key %#v", other, name, limiter.Key)
+			}
+			// Only the global rate limits are meant to be shared
+			if limiter.Key.Name != "partners" {
+				keys[limiter.Key] = name
+			}
+		}
+	}
+
+	equals(t, map[LimiterKey]string{
+		{"apis/shop|all", "plain", "service"}:                          "shop",
+		{"apis/shop|per-client|{{remote_addr}}", "liquid", "global"}:   "shop",
+		{"apis/outlet|all", "plain", "service"}:                        "outlet",
+		{"apis/outlet|per-client|{{remote_addr}}", "liquid", "global"}: "outlet",
+	}, keys)
+}
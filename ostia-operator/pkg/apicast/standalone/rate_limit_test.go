@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
 )
 
 func TestProcessRateLimits(t *testing.T) {
@@ -63,10 +64,27 @@ func TestProcessRateLimits(t *testing.T) {
 			},
 			shouldContain: `{"fixed_window_limiters":[{"count":100,"key":{"name":"testing_default_time","name_type":"plain","scope":"service"},"window":1}]}}`,
 		},
+		{
+			mockCrdDefinition: []byte(`{"type":"FixedWindow","name":"gets","limit":"10/s","conditions":{"operation":"or","operations":[{"http_method":"GET"},{"request_path":"/orders","op":"!="}]}}`),
+			expect: FixedWindowRateLimiter{
+				Window: 1,
+				Count:  10,
+				Key:    LimiterKey{"gets", "plain", "service"},
+				Condition: &PolicyCondition{CombineOp: "or", Operations: []Operation{
+					{Left: "{{http_method}}", LeftType: "liquid", Op: "==", Right: "GET"},
+					{Left: "{{uri}}", LeftType: "liquid", Op: "!=", Right: "/orders"},
+				}},
+			},
+			shouldContain: `"condition":{"operations":[{"left":"{{http_method}}","left_type":"liquid","op":"==","right":"GET"},{"left":"{{uri}}","left_type":"liquid","op":"!=","right":"/orders"}],"combine_op":"or"}`,
+		},
 		{
 			mockCrdDefinition: []byte(`{"type":"FixedWindow","name":"expect_err","limit":"ten"}`),
 			expectErr:         true,
 		},
+		{
+			mockCrdDefinition: []byte(`{"type":"FixedWindow","name":"unknown_unit","limit":"10/week"}`),
+			expectErr:         true,
+		},
 	}
 	for _, input := range fixedRateInputs {
 		result, err := policyFromRlJson(input.mockCrdDefinition)
@@ -95,9 +113,17 @@ func TestProcessRateLimits(t *testing.T) {
 			expect: LeakyBucketRateLimiter{
 				Burst: 0,
 				Key:   LimiterKey{"fixed", "plain", "service"},
-				Rate:  1,
+				Rate:  100.0 / 60,
 			},
-			shouldContain: `{"leaky_bucket_limiters":[{"burst":0,"key":{"name":"fixed","name_type":"plain","scope":"service"},"rate":1}]}`,
+			shouldContain: `{"leaky_bucket_limiters":[{"burst":0,"key":{"name":"fixed","name_type":"plain","scope":"service"},"rate":1.6666666666666667}]}`,
+		},
+		{
+			mockCrdDefinition: []byte(`{"type":"LeakyBucket","name":"daily","limit":"1728/day"}`),
+			expect: LeakyBucketRateLimiter{
+				Key:  LimiterKey{"daily", "plain", "service"},
+				Rate: 0.02,
+			},
+			shouldContain: `"rate":0.02}`,
 		},
 		{
 			mockCrdDefinition: []byte(`{"type":"LeakyBucket","name":"fixed","limit":"120/m","burst":20}`),
@@ -188,6 +214,140 @@ func TestProcessRateLimits(t *testing.T) {
 	}
 }
 
+func TestParseTimeLimits(t *testing.T) {
+	inputs := []struct {
+		limit         string
+		expectErr     bool
+		expectCount   int
+		expectSeconds int
+	}{
+		{limit: "100", expectCount: 100, expectSeconds: 1},
+		{limit: "100/s", expectCount: 100, expectSeconds: 1},
+		{limit: "5/10s", expectCount: 5, expectSeconds: 10},
+		{limit: "30/m", expectCount: 30, expectSeconds: 60},
+		{limit: "30/min", expectCount: 30, expectSeconds: 60},
+		{limit: "3600/hr", expectCount: 3600, expectSeconds: 3600},
+		{limit: "1000/day", expectCount: 1000, expectSeconds: 86400},
+		{limit: "1000/2d", expectCount: 1000, expectSeconds: 2 * 86400},
+		{limit: "100/1h30m", expectCount: 100, expectSeconds: 5400},
+		{limit: "100/1.5h", expectCount: 100, expectSeconds: 5400},
+		{limit: "", expectErr: true},
+		{limit: "0/s", expectErr: true},
+		{limit: "-1/s", expectErr: true},
+		{limit: "ten/s", expectErr: true},
+		{limit: "10/", expectErr: true},
+		{limit: "10/0s", expectErr: true},
+		{limit: "10/week", expectErr: true},
+		{limit: "10/500ms", expectErr: true},
+		{limit: "10/1.5s", expectErr: true},
+		{limit: "10/-1h", expectErr: true},
+	}
+
+	for _, input := range inputs {
+		count, seconds, err := parseTimeLimits(ostia.RateLimit{Name: "test", Limit: input.limit})
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for limit %q", input.limit)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for limit %q - %s", input.limit, err)
+			continue
+		}
+		if count != input.expectCount || seconds != input.expectSeconds {
+			t.Errorf("limit %q - expected %d/%ds, got %d/%ds", input.limit, input.expectCount, input.expectSeconds, count, seconds)
+		}
+	}
+}
+
+func TestEndpointRateLimits(t *testing.T) {
+	api := &ostia.API{Spec: ostia.APISpec{RateLimits: []ostia.RateLimit{
+		{Name: "all", Type: "FixedWindow", Limit: "100/s"},
+		{Name: "per-client", Type: "FixedWindow", Limit: "10/s", Source: "{{remote_addr}}"},
+		{Name: "each", Type: "FixedWindow", Limit: "50/s", Scope: ostia.RateLimitScopeEndpoint},
+	}}}
+	api.Name = "shop"
+	api.Namespace = "apis"
+	endpoint := ostia.Endpoint{Name: "orders", RateLimits: []ostia.RateLimit{
+		{Name: "per-client", Type: "FixedWindow", Limit: "1/s", Source: "{{remote_addr}}"},
+		{Name: "slow", Type: "ConnectionBased", Conn: &[]int{10}[0]},
+		{Name: "orders", Type: "FixedWindow", Limit: "10/s", Scope: ostia.RateLimitScopeAPI},
+	}}
+
+	limits, err := endpointRateLimits(api, endpoint)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	// The rate limits of the API are enforced on each Endpoint apart unless they ask for the api scope
+	equals(t, []scopedRateLimit{
+		{api.Spec.RateLimits[0], "service", "apis/shop|"},
+		{api.Spec.RateLimits[2], "service", "apis/shop|"},
+		{endpoint.RateLimits[0], "service", "apis/shop|"},
+		{endpoint.RateLimits[1], "service", "apis/shop|"},
+		{endpoint.RateLimits[2], "global", "apis/shop|"},
+	}, limits)
+
+	policy, err := processScopedRateLimitPolicies(limits, nil, rateLimitSettings{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	var keys []LimiterKey
+	for _, limiter := range *policy.Configuration.(RateLimitPolicyConfiguration).FixedWindowLimiters {
+		keys = append(keys, limiter.Key)
+	}
+	equals(t, []LimiterKey{
+		{"apis/shop|all", "plain", "service"},
+		{"apis/shop|each", "plain", "service"},
+		{"apis/shop|{{remote_addr}}", "liquid", "service"},
+		{"apis/shop|orders", "plain", "global"},
+	}, keys)
+
+	// Global rate limits are shared with other APIs through the rate limit store
+	global := ostia.Endpoint{Name: "global", RateLimits: []ostia.RateLimit{
+		{Name: "partners", Type: "FixedWindow", Limit: "1000/m", Scope: ostia.RateLimitScopeGlobal},
+	}}
+	if _, err := endpointRateLimits(api, global); err == nil {
+		t.Error("expected error for global rate limit without rate limit store")
+	}
+	// Every API gets its own managed store, the counters can't be shared
+	api.Spec.RateLimitStore = &ostia.RateLimitStore{Managed: true}
+	if _, err := endpointRateLimits(api, global); err == nil {
+		t.Error("expected error for global rate limit with a managed rate limit store")
+	}
+	api.Spec.RateLimitStore = &ostia.RateLimitStore{URLSecretRef: &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "redis"}, Key: "url",
+	}}
+	limits, err = endpointRateLimits(api, global)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	key, _ := parseLimiterKey(limits[len(limits)-1], nil)
+	equals(t, LimiterKey{"partners", "plain", "global"}, key)
+
+	unknown := ostia.Endpoint{Name: "unknown", RateLimits: []ostia.RateLimit{{Name: "x", Scope: "cluster"}}}
+	if _, err := endpointRateLimits(api, unknown); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestCheckLimiterKeys(t *testing.T) {
+	perClient := ostia.RateLimit{Name: "per-client", Type: "FixedWindow", Limit: "10/s", Source: "{{remote_addr}}"}
+	burst := ostia.RateLimit{Name: "burst", Type: "LeakyBucket", Limit: "1/s", Source: "{{remote_addr}}"}
+
+	// the same key in different scopes counts apart
+	if err := checkLimiterKeys([]scopedRateLimit{{perClient, "service", ""}, {burst, "global", ""}}, nil); err != nil {
+		t.Errorf("unexpected error - %s", err)
+	}
+
+	if err := checkLimiterKeys([]scopedRateLimit{{perClient, "service", ""}, {burst, "service", ""}}, nil); err == nil {
+		t.Error("expected error for rate limits sharing key and scope")
+	}
+	if _, err := processScopedRateLimitPolicies([]scopedRateLimit{{perClient, "service", ""}, {burst, "service", ""}}, nil, rateLimitSettings{}); err == nil {
+		t.Error("expected error processing rate limits sharing key and scope")
+	}
+}
+
 func equals(tb testing.TB, exp, act interface{}) {
 	if !reflect.DeepEqual(exp, act) {
 		_, file, line, _ := runtime.Caller(1)
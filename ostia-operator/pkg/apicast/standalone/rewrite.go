@@ -0,0 +1,69 @@
+package standalone
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const urlRewritingPolicyName = "apicast.policy.url_rewriting"
+
+// processRewrite returns the policy changing the request path before it is proxied to the Endpoint host
+func processRewrite(endpoint ostia.Endpoint) (Policy, error) {
+	var policy Policy
+
+	command, err := rewriteCommand(endpoint)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.Name = urlRewritingPolicyName
+	policy.Configuration = URLRewritingPolicyConfiguration{Commands: []URLRewritingCommand{command}}
+
+	return policy, nil
+}
+
+func rewriteCommand(endpoint ostia.Endpoint) (URLRewritingCommand, error) {
+	rewrite := endpoint.Rewrite
+	command := URLRewritingCommand{Op: "sub", Break: true}
+
+	options := 0
+	for _, set := range []bool{rewrite.StripPrefix, rewrite.ReplacePrefix != "", rewrite.Regex != nil} {
+		if set {
+			options++
+		}
+	}
+	if options != 1 {
+		return command, fmt.Errorf("exactly one of 'stripPrefix', 'replacePrefix' or 'regex' has to be set on endpoint %s", endpoint.Name)
+	}
+
+	if rewrite.Regex != nil {
+		if _, err := regexp.Compile(rewrite.Regex.Pattern); err != nil || rewrite.Regex.Pattern == "" {
+			return command, fmt.Errorf("invalid rewrite pattern '%s' on endpoint %s", rewrite.Regex.Pattern, endpoint.Name)
+		}
+		command.Regex = rewrite.Regex.Pattern
+		command.Replace = rewrite.Regex.Replacement
+		return command, nil
+	}
+
+	prefix := strings.TrimSuffix(endpoint.Path, "/")
+
+	if rewrite.StripPrefix {
+		// Keep the path absolute, /hello and /hello/ both become /
+		command.Regex = "^" + regexp.QuoteMeta(prefix) + "/?"
+		command.Replace = "/"
+		return command, nil
+	}
+
+	command.Regex = "^" + regexp.QuoteMeta(prefix)
+	command.Replace = strings.TrimSuffix(rewrite.ReplacePrefix, "/")
+	if command.Replace == "" {
+		// replacePrefix is /
+		command.Regex += "/?"
+		command.Replace = "/"
+	}
+
+	return command, nil
+}
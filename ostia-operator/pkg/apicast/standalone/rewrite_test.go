@@ -0,0 +1,60 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessRewrite(t *testing.T) {
+	inputs := []struct {
+		endpoint  ostia.Endpoint
+		expectErr bool
+		expect    URLRewritingCommand
+	}{
+		{
+			endpoint: ostia.Endpoint{Path: "/hello", Rewrite: &ostia.Rewrite{StripPrefix: true}},
+			expect:   URLRewritingCommand{Op: "sub", Regex: "^/hello/?", Replace: "/", Break: true},
+		},
+		{
+			endpoint: ostia.Endpoint{Path: "/v1.0/", Rewrite: &ostia.Rewrite{ReplacePrefix: "/api/v2/"}},
+			expect:   URLRewritingCommand{Op: "sub", Regex: `^/v1\.0`, Replace: "/api/v2", Break: true},
+		},
+		{
+			endpoint: ostia.Endpoint{Path: "/hello", Rewrite: &ostia.Rewrite{ReplacePrefix: "/"}},
+			expect:   URLRewritingCommand{Op: "sub", Regex: "^/hello/?", Replace: "/", Break: true},
+		},
+		{
+			endpoint: ostia.Endpoint{Path: "/users",
+				Rewrite: &ostia.Rewrite{Regex: &ostia.RegexRewrite{Pattern: "^/users/([0-9]+)", Replacement: "/accounts/$1"}}},
+			expect: URLRewritingCommand{Op: "sub", Regex: "^/users/([0-9]+)", Replace: "/accounts/$1", Break: true},
+		},
+		{
+			endpoint:  ostia.Endpoint{Path: "/hello", Rewrite: &ostia.Rewrite{StripPrefix: true, ReplacePrefix: "/world"}},
+			expectErr: true,
+		},
+		{
+			endpoint:  ostia.Endpoint{Path: "/hello", Rewrite: &ostia.Rewrite{}},
+			expectErr: true,
+		},
+		{
+			endpoint:  ostia.Endpoint{Path: "/hello", Rewrite: &ostia.Rewrite{Regex: &ostia.RegexRewrite{Pattern: "(", Replacement: "/"}}},
+			expectErr: true,
+		},
+	}
+
+	for _, input := range inputs {
+		policy, err := processRewrite(input.endpoint)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.endpoint.Rewrite)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		equals(t, urlRewritingPolicyName, policy.Name)
+		equals(t, URLRewritingPolicyConfiguration{Commands: []URLRewritingCommand{input.expect}}, policy.Configuration)
+	}
+}
@@ -0,0 +1,139 @@
+package standalone
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// pathMatchTypes are the path matches APIcast's router can do, it only compares the path with uri_path
+var pathMatchTypes = []string{string(ostia.PathMatchPrefix)}
+
+func isHTTPMethod(method string) bool {
+	for _, m := range httpMethods {
+		if m == strings.ToUpper(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeCandidate is a route with what is needed to sort it by precedence
+type routeCandidate struct {
+	route      Route
+	path       string
+	restricted bool // only for some methods
+	fallback   bool // answers 405 to the methods not served
+}
+
+// endpointRoutes returns the routes of the endpoints to the services in destinations, indexed like endpoints.
+//
+// Routes are ordered by precedence, the first one matching the request wins:
+//  1. prefixes from the longest to the shortest
+//  2. for the same path, routes for a specific hostname go first
+//  3. then routes for specific methods and then routes for any method
+//
+// The 405 for the methods not served go after all of them, so they only answer the requests no other
+// Endpoint can take, like a DELETE of /api/orders when only GET is served there and under /api.
+func endpointRoutes(endpoints []ostia.Endpoint, destinations []string) []Route {
+	var candidates []routeCandidate
+
+	type match struct {
+		path string
+		host string
+	}
+	var restrictedMatches []match
+	// restrictedBy holds the first Endpoint restricting the methods of a match, which names its 405 route
+	restrictedBy := make(map[match]string)
+	served := make(map[match]bool)
+
+	for i, endpoint := range endpoints {
+		m := match{endpoint.Path, endpoint.Hostname}
+
+		if len(endpoint.Methods) == 0 {
+			candidates = append(candidates, routeCandidate{
+				route: endpointRoute(endpoint.Name, m.path, m.host, "", Destination{Service: destinations[i]}),
+				path:  m.path,
+			})
+			served[m] = true
+			continue
+		}
+
+		for _, method := range endpoint.Methods {
+			candidates = append(candidates, routeCandidate{
+				route:      endpointRoute(endpoint.Name, m.path, m.host, strings.ToUpper(method), Destination{Service: destinations[i]}),
+				path:       m.path,
+				restricted: true,
+			})
+		}
+		if _, ok := restrictedBy[m]; !ok {
+			restrictedBy[m] = endpoint.Name
+			restrictedMatches = append(restrictedMatches, m)
+		}
+	}
+
+	for _, m := range restrictedMatches {
+		if served[m] {
+			continue
+		}
+
+		candidates = append(candidates, routeCandidate{
+			route:    endpointRoute("method-not-allowed:"+restrictedBy[m], m.path, m.host, "", Destination{HTTPResponse: http.StatusMethodNotAllowed}),
+			path:     m.path,
+			fallback: true,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return precedes(candidates[i], candidates[j])
+	})
+
+	routes := make([]Route, 0, len(candidates))
+	for _, c := range candidates {
+		routes = append(routes, c.route)
+	}
+
+	return routes
+}
+
+func pathMatchOf(endpoint ostia.Endpoint) ostia.PathMatchType {
+	if endpoint.PathMatch == "" {
+		return ostia.DefaultPathMatch
+	}
+	return endpoint.PathMatch
+}
+
+func endpointRoute(name string, path string, host string, method string, destination Destination) Route {
+	return Route{
+		Name:        name,
+		Match:       Match{URIPath: path, HTTPMethod: method, HTTPHost: host, ServerPort: "default"},
+		Destination: destination,
+	}
+}
+
+// precedes tells if route a has to be evaluated before route b
+func precedes(a, b routeCandidate) bool {
+	if a.fallback != b.fallback {
+		return b.fallback
+	}
+
+	if len(a.path) != len(b.path) {
+		return len(a.path) > len(b.path)
+	}
+	if a.path != b.path {
+		return a.path < b.path
+	}
+
+	if ha, hb := a.route.Match.HTTPHost != "", b.route.Match.HTTPHost != ""; ha != hb {
+		return ha
+	}
+
+	return a.restricted && !b.restricted
+}
@@ -0,0 +1,84 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestEndpointRoutes(t *testing.T) {
+	endpoints := []ostia.Endpoint{
+		{Name: "list-orders", Path: "/orders", Methods: []string{"get", "HEAD"}},
+		{Name: "hello", Path: "/hello"},
+		{Name: "create-orders", Path: "/orders", Methods: []string{"POST"}},
+		{Name: "admin", Path: "/admin", Hostname: "admin.example.com", Methods: []string{"GET"}},
+		{Name: "status", Path: "/status", Methods: []string{"GET"}},
+		{Name: "status-any", Path: "/status"},
+	}
+	destinations := []string{"orders-read", "hello", "orders-write", "admin", "status", "status"}
+
+	expect := []Route{
+		{Name: "list-orders", Match: Match{URIPath: "/orders", HTTPMethod: "GET", ServerPort: "default"}, Destination: Destination{Service: "orders-read"}},
+		{Name: "list-orders", Match: Match{URIPath: "/orders", HTTPMethod: "HEAD", ServerPort: "default"}, Destination: Destination{Service: "orders-read"}},
+		{Name: "create-orders", Match: Match{URIPath: "/orders", HTTPMethod: "POST", ServerPort: "default"}, Destination: Destination{Service: "orders-write"}},
+		{Name: "status", Match: Match{URIPath: "/status", HTTPMethod: "GET", ServerPort: "default"}, Destination: Destination{Service: "status"}},
+		{Name: "status-any", Match: Match{URIPath: "/status", ServerPort: "default"}, Destination: Destination{Service: "status"}},
+		{Name: "admin", Match: Match{URIPath: "/admin", HTTPMethod: "GET", HTTPHost: "admin.example.com", ServerPort: "default"}, Destination: Destination{Service: "admin"}},
+		{Name: "hello", Match: Match{URIPath: "/hello", ServerPort: "default"}, Destination: Destination{Service: "hello"}},
+		{Name: "method-not-allowed:list-orders", Match: Match{URIPath: "/orders", ServerPort: "default"}, Destination: Destination{HTTPResponse: 405}},
+		{Name: "method-not-allowed:admin", Match: Match{URIPath: "/admin", HTTPHost: "admin.example.com", ServerPort: "default"}, Destination: Destination{HTTPResponse: 405}},
+	}
+
+	equals(t, expect, endpointRoutes(endpoints, destinations))
+}
+
+func TestEndpointRoutesPrecedence(t *testing.T) {
+	endpoints := []ostia.Endpoint{
+		{Name: "catch-all", Path: "/"},
+		{Name: "api", Path: "/api", Methods: []string{"GET"}},
+		{Name: "api-v1", Path: "/api/v1", Hostname: "api.example.com", Methods: []string{"GET"}},
+		{Name: "orders", Path: "/api/orders", Methods: []string{"GET"}},
+		{Name: "api-host", Path: "/api", Hostname: "api.example.com"},
+		{Name: "legacy-delete", Path: "/api/v1", Methods: []string{"DELETE"}},
+		{Name: "orders-write", Path: "/api/orders", Methods: []string{"POST"}},
+		{Name: "api-host-post", Path: "/api", Hostname: "api.example.com", Methods: []string{"POST"}},
+	}
+	destinations := []string{"catch-all", "api", "api-v1", "orders", "api-host", "legacy-delete", "orders-write", "api-host-post"}
+
+	route := func(name string, path string, host string, method string) Route {
+		return Route{Name: name, Match: Match{URIPath: path, HTTPHost: host, HTTPMethod: method, ServerPort: "default"}, Destination: Destination{Service: name}}
+	}
+	methodNotAllowed := func(name string, path string, host string) Route {
+		return Route{Name: "method-not-allowed:" + name, Match: Match{URIPath: path, HTTPHost: host, ServerPort: "default"}, Destination: Destination{HTTPResponse: 405}}
+	}
+
+	expect := []Route{
+		route("orders", "/api/orders", "", "GET"),
+		route("orders-write", "/api/orders", "", "POST"),
+		route("api-v1", "/api/v1", "api.example.com", "GET"),
+		route("legacy-delete", "/api/v1", "", "DELETE"),
+		route("api-host-post", "/api", "api.example.com", "POST"),
+		route("api-host", "/api", "api.example.com", ""),
+		route("api", "/api", "", "GET"),
+		route("catch-all", "/", "", ""),
+		// A DELETE of /api/orders goes to catch-all, only the requests no route takes get a 405
+		methodNotAllowed("orders", "/api/orders", ""),
+		methodNotAllowed("api-v1", "/api/v1", "api.example.com"),
+		methodNotAllowed("legacy-delete", "/api/v1", ""),
+		methodNotAllowed("api", "/api", ""),
+	}
+
+	routes := endpointRoutes(endpoints, destinations)
+	equals(t, expect, routes)
+
+	names := make(map[string]bool)
+	for _, route := range routes {
+		if route.Destination.HTTPResponse == 0 {
+			continue
+		}
+		if names[route.Name] {
+			t.Errorf("duplicated route name %s", route.Name)
+		}
+		names[route.Name] = true
+	}
+}
@@ -0,0 +1,165 @@
+package standalone
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonSchema is the subset of JSON Schema checked by the operator. Any other keyword, like format or dependencies,
+// is ignored when parsing, so the checks are partial.
+type jsonSchema struct {
+	Type                 interface{}            `json:"type"` // a type name or a list of them
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	MinItems             *int                   `json:"minItems"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+}
+
+func parseSchema(schema string) (*jsonSchema, error) {
+	var s jsonSchema
+	err := json.Unmarshal([]byte(schema), &s)
+	return &s, err
+}
+
+// validate returns the errors found checking value, as decoded by encoding/json, against the schema
+func (s *jsonSchema) validate(value interface{}, path string) []error {
+	var errs []error
+
+	if !s.matchesType(value) {
+		return append(errs, fmt.Errorf("%s: must be of type %v", path, s.Type))
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: must be one of %v", path, s.Enum))
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		matches := 0
+		for _, option := range s.OneOf {
+			if len(option.validate(value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			errs = append(errs, fmt.Errorf("%s: must match exactly one of the allowed schemas", path))
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs = append(errs, fmt.Errorf("%s: must be greater than or equal to %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs = append(errs, fmt.Errorf("%s: must be less than or equal to %v", path, *s.Maximum))
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			errs = append(errs, fmt.Errorf("%s: must be at least %d characters long", path, *s.MinLength))
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, fmt.Errorf("%s: must have at least %d items", path, *s.MinItems))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s.%s: is required", path, name))
+			}
+		}
+
+		// sorted so the errors are stable
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				errs = append(errs, property.validate(v[name], path+"."+name)...)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, fmt.Errorf("%s.%s: is not a known property", path, name))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s *jsonSchema) matchesType(value interface{}) bool {
+	var types []string
+
+	switch t := s.Type.(type) {
+	case nil:
+		return true
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			types = append(types, fmt.Sprint(name))
+		}
+	}
+
+	for _, name := range types {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && v == float64(int64(v))) {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// joinErrors formats the schema errors as a single message
+func joinErrors(errs []error) string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, ", ")
+}
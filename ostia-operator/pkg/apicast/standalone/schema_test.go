@@ -0,0 +1,62 @@
+package standalone
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := parseSchema(`{
+		"type": "object",
+		"required": ["name"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"count": {"type": "integer", "minimum": 1},
+			"mode": {"type": "string", "enum": ["a", "b"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"value": {"oneOf": [{"type": "string"}, {"type": "number"}]}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("error parsing schema - %s", err)
+	}
+
+	inputs := []struct {
+		value       string
+		expectCount int
+	}{
+		{`{"name":"test","count":2,"mode":"a","tags":["x"],"value":1.5}`, 0},
+		{`{"name":"","count":1.5}`, 2},
+		{`{"count":0,"mode":"c","extra":true}`, 4},
+		{`{"name":"test","tags":["x",1],"value":true}`, 2},
+		{`[]`, 1},
+	}
+
+	for _, input := range inputs {
+		var value interface{}
+		if err := json.Unmarshal([]byte(input.value), &value); err != nil {
+			t.Fatalf("error unmarshalling value - %s", err)
+		}
+
+		if errs := schema.validate(value, "config"); len(errs) != input.expectCount {
+			t.Errorf("expected %d errors for %s, got %v", input.expectCount, input.value, errs)
+		}
+	}
+}
+
+func TestBundledSchemas(t *testing.T) {
+	for name, schema := range policySchemas {
+		if _, err := parseSchema(schema); err != nil {
+			t.Errorf("bundled schema of %s can't be parsed - %s", name, err)
+		}
+
+		// Keywords jsonSchema doesn't know would be silently ignored
+		decoder := json.NewDecoder(strings.NewReader(schema))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&jsonSchema{}); err != nil {
+			t.Errorf("bundled schema of %s uses keywords that aren't checked - %s", name, err)
+		}
+	}
+}
@@ -51,7 +51,7 @@ type TLS struct {
 	Protocols      string   `json:"protocols"`
 	Certificate    string   `json:"certificate"`
 	CertificateKey string   `json:"certificate_key"`
-	Ciphers        []string `json:"ciphers"`
+	Ciphers        []string `json:"ciphers,omitempty"`
 }
 
 type Route struct {
@@ -77,6 +77,7 @@ type Destination struct {
 
 type Policy struct {
 	Name          string              `json:"policy"`
+	Version       string              `json:"version,omitempty"`
 	Configuration PolicyConfiguration `json:"configuration,omitempty"`
 }
 
@@ -106,11 +107,198 @@ func NewUpstream(server string) Upstream {
 	return upstream
 }
 
+// ConditionalPolicyConfiguration runs the nested PolicyChain only when Condition holds true
+type ConditionalPolicyConfiguration struct {
+	Condition   PolicyCondition `json:"condition"`
+	PolicyChain []NestedPolicy  `json:"policy_chain"`
+}
+
+var _ PolicyConfiguration = (*ConditionalPolicyConfiguration)(nil)
+
+// PolicyCondition combines a group of operations with CombineOp (and / or)
+type PolicyCondition struct {
+	Operations []Operation `json:"operations"`
+	CombineOp  string      `json:"combine_op,omitempty"`
+}
+
+// Operation compares Left with Right, both sides can be plain values or liquid templates
+type Operation struct {
+	Left      string `json:"left"`
+	LeftType  string `json:"left_type,omitempty"`
+	Op        string `json:"op"`
+	Right     string `json:"right"`
+	RightType string `json:"right_type,omitempty"`
+}
+
+// NestedPolicy is a policy inside the chain of another policy, those are referenced by name
+type NestedPolicy struct {
+	Name          string              `json:"name"`
+	Configuration PolicyConfiguration `json:"configuration,omitempty"`
+}
+
+// EchoPolicyConfiguration makes APIcast answer with Status instead of calling the upstream
+type EchoPolicyConfiguration struct {
+	Status uint16 `json:"status"`
+	Exit   string `json:"exit"` // request / set
+}
+
+var _ PolicyConfiguration = (*EchoPolicyConfiguration)(nil)
+
+// OIDCAuthenticationPolicyConfiguration verifies the bearer token with the keys discovered from IssuerEndpoint,
+// the decoded token is then available to the next policies as jwt
+type OIDCAuthenticationPolicyConfiguration struct {
+	IssuerEndpoint string `json:"issuer_endpoint"`
+	Required       bool   `json:"required"`      // Rejects the requests without a token
+	TTL            int32  `json:"ttl,omitempty"` // Seconds the discovered keys are cached
+}
+
+var _ PolicyConfiguration = (*OIDCAuthenticationPolicyConfiguration)(nil)
+
+// JWTClaimCheckPolicyConfiguration rejects the requests whose token doesn't satisfy every rule matching them
+type JWTClaimCheckPolicyConfiguration struct {
+	Rules        []JWTClaimCheckRule `json:"rules"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+}
+
+var _ PolicyConfiguration = (*JWTClaimCheckPolicyConfiguration)(nil)
+
+// JWTClaimCheckRule applies to the requests with one of the Methods and a path matching Resource
+type JWTClaimCheckRule struct {
+	Resource     string              `json:"resource"`
+	ResourceType string              `json:"resource_type,omitempty"`
+	Methods      []string            `json:"methods,omitempty"`
+	Operations   []JWTClaimOperation `json:"operations"`
+	CombineOp    string              `json:"combine_op,omitempty"`
+}
+
+// JWTClaimOperation compares the token claim JWTClaim with Value
+type JWTClaimOperation struct {
+	Op           string `json:"op"`
+	JWTClaim     string `json:"jwt_claim"`
+	JWTClaimType string `json:"jwt_claim_type,omitempty"`
+	Value        string `json:"value"`
+	ValueType    string `json:"value_type,omitempty"`
+}
+
+// UpstreamMTLSPolicyConfiguration configures the TLS connection to the upstream.
+// The client certificate and key are paths in the container, the CA certificates are PEM encoded.
+type UpstreamMTLSPolicyConfiguration struct {
+	CertificateType    string   `json:"certificate_type,omitempty"`
+	Certificate        string   `json:"certificate,omitempty"`
+	CertificateKeyType string   `json:"certificate_key_type,omitempty"`
+	CertificateKey     string   `json:"certificate_key,omitempty"`
+	Verify             bool     `json:"verify"`
+	CACertificates     []string `json:"ca_certificates,omitempty"`
+}
+
+var _ PolicyConfiguration = (*UpstreamMTLSPolicyConfiguration)(nil)
+
+// HeadersPolicyConfiguration modifies the request headers sent upstream and the response headers sent downstream
+type HeadersPolicyConfiguration struct {
+	Request  []HeaderCommand `json:"request,omitempty"`
+	Response []HeaderCommand `json:"response,omitempty"`
+}
+
+var _ PolicyConfiguration = (*HeadersPolicyConfiguration)(nil)
+
+// HeaderCommand is a push, set or delete operation on a header, Value is rendered as liquid when ValueType is liquid
+type HeaderCommand struct {
+	Op        string `json:"op"`
+	Header    string `json:"header"`
+	Value     string `json:"value,omitempty"`
+	ValueType string `json:"value_type,omitempty"`
+}
+
+// CORSPolicyConfiguration answers preflight requests and adds the CORS headers to responses,
+// the Origin of the request is allowed when AllowOrigin is empty
+type CORSPolicyConfiguration struct {
+	AllowOrigin      string   `json:"allow_origin,omitempty"`
+	AllowMethods     []string `json:"allow_methods,omitempty"`
+	AllowHeaders     []string `json:"allow_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           *int32   `json:"max_age,omitempty"`
+}
+
+var _ PolicyConfiguration = (*CORSPolicyConfiguration)(nil)
+
+// IPCheckPolicyConfiguration accepts (whitelist) or rejects (blacklist) the requests from IPs
+type IPCheckPolicyConfiguration struct {
+	IPs             []string `json:"ips"`
+	CheckType       string   `json:"check_type"`
+	ErrorMessage    string   `json:"error_msg,omitempty"`
+	ClientIPSources []string `json:"client_ip_sources,omitempty"`
+}
+
+var _ PolicyConfiguration = (*IPCheckPolicyConfiguration)(nil)
+
+// URLRewritingPolicyConfiguration applies the commands to the request path in order
+type URLRewritingPolicyConfiguration struct {
+	Commands []URLRewritingCommand `json:"commands"`
+}
+
+var _ PolicyConfiguration = (*URLRewritingPolicyConfiguration)(nil)
+
+// URLRewritingCommand replaces the first (sub) or every (gsub) match of Regex with Replace
+type URLRewritingCommand struct {
+	Op      string `json:"op"`
+	Regex   string `json:"regex"`
+	Replace string `json:"replace"`
+	Break   bool   `json:"break,omitempty"`
+}
+
+// UpstreamConnectionPolicyConfiguration sets the timeouts, in seconds, of the connection to the upstream
+type UpstreamConnectionPolicyConfiguration struct {
+	ConnectTimeout int `json:"connect_timeout,omitempty"`
+	SendTimeout    int `json:"send_timeout,omitempty"`
+	ReadTimeout    int `json:"read_timeout,omitempty"`
+}
+
+var _ PolicyConfiguration = (*UpstreamConnectionPolicyConfiguration)(nil)
+
+// RetryPolicyConfiguration sets how many more times a failed request is sent to the upstream
+type RetryPolicyConfiguration struct {
+	Retries int `json:"retries"`
+}
+
+var _ PolicyConfiguration = (*RetryPolicyConfiguration)(nil)
+
+// ContentCachingPolicyConfiguration caches the responses as told by the first rule whose condition holds true.
+// The size of the cache and how long responses are kept are nginx settings, not part of the policy.
+type ContentCachingPolicyConfiguration struct {
+	Rules []ContentCachingRule `json:"rules"`
+}
+
+// ContentCachingRule enables or disables the cache, and reports in Header whether it was hit
+type ContentCachingRule struct {
+	Cache     bool            `json:"cache"`
+	Header    string          `json:"header,omitempty"`
+	Condition PolicyCondition `json:"condition"`
+}
+
+var _ PolicyConfiguration = (*ContentCachingPolicyConfiguration)(nil)
+
+// PayloadLimitsPolicyConfiguration rejects the requests with a body over Request bytes and the responses
+// with a body over Response bytes, 0 means no limit
+type PayloadLimitsPolicyConfiguration struct {
+	Request  int64 `json:"request,omitempty"`
+	Response int64 `json:"response,omitempty"`
+}
+
+var _ PolicyConfiguration = (*PayloadLimitsPolicyConfiguration)(nil)
+
 // PolicyChainConfiguration contains a group of PolicyChainRule
 type RateLimitPolicyConfiguration struct {
 	FixedWindowLimiters *[]FixedWindowRateLimiter `json:"fixed_window_limiters,omitempty"`
 	LeakyBucketLimiters *[]LeakyBucketRateLimiter `json:"leaky_bucket_limiters,omitempty"`
 	ConnectionLimiters  *[]ConnectionRateLimiter  `json:"connection_limiters,omitempty"`
+	RedisURL            string                    `json:"redis_url,omitempty"` // Shares the counters among the replicas
+	LimitsExceededError *LimitsExceededError      `json:"limits_exceeded_error,omitempty"`
+}
+
+// LimitsExceededError is the response to the requests over a limit
+type LimitsExceededError struct {
+	StatusCode    int    `json:"status_code"`
+	ErrorHandling string `json:"error_handling"` // "exit" rejects the request, "log" only logs it
 }
 
 var _ PolicyConfiguration = (*RateLimitPolicyConfiguration)(nil)
@@ -119,7 +307,7 @@ var _ PolicyConfiguration = (*RateLimitPolicyConfiguration)(nil)
 // Based on a fixed window of time (last X seconds).
 // Can make up to Count requests per Window seconds.
 type FixedWindowRateLimiter struct {
-	Condition *ostia.Condition `json:"condition,omitempty"`
+	Condition *PolicyCondition `json:"condition,omitempty"`
 	Count     int              `json:"count"`
 	Key       LimiterKey       `json:"key"`
 	Window    int              `json:"window"`
@@ -127,14 +315,14 @@ type FixedWindowRateLimiter struct {
 
 //LeakyBucketRateLimiter defines a leaky bucket rate limiting rule
 // Based on "leaky bucket" algorithm (average number of requests plus a maximum burst size)
-// Can make up to Rate requests per second.
+// Can make up to Rate requests per second, a fraction for limits over longer windows.
 // It allows exceeding that number by Burst requests per second
 // An artificial delay is introduced for those requests between rate and burst to avoid going over the limits.
 type LeakyBucketRateLimiter struct {
 	Burst     int              `json:"burst"`
-	Condition *ostia.Condition `json:"condition,omitempty"`
+	Condition *PolicyCondition `json:"condition,omitempty"`
 	Key       LimiterKey       `json:"key"`
-	Rate      int              `json:"rate"`
+	Rate      float64          `json:"rate"`
 }
 
 //ConnectionRateLimiter defines a connection rate, rate limiting rule
@@ -144,7 +332,7 @@ type LeakyBucketRateLimiter struct {
 // Delay is the number of seconds to delay the connections that exceed the limit.
 type ConnectionRateLimiter struct {
 	Burst     int              `json:"burst"`
-	Condition *ostia.Condition `json:"condition,omitempty"`
+	Condition *PolicyCondition `json:"condition,omitempty"`
 	Conn      int              `json:"conn"`
 	Delay     int              `json:"delay"`
 	Key       LimiterKey       `json:"key"`
@@ -0,0 +1,48 @@
+package standalone
+
+import (
+	"fmt"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+const (
+	upstreamConnectionPolicyName = "apicast.policy.upstream_connection"
+	retryPolicyName              = "apicast.policy.retry"
+
+	// maxRetries is the most retries the APIcast retry policy accepts
+	maxRetries = 10
+)
+
+// processTimeouts returns the policy limiting how long APIcast waits on the upstream of the Endpoint
+func processTimeouts(endpoint ostia.Endpoint) (Policy, error) {
+	var policy Policy
+	timeouts := endpoint.Timeouts
+
+	if timeouts.ConnectSeconds < 0 || timeouts.SendSeconds < 0 || timeouts.ReadSeconds < 0 {
+		return policy, fmt.Errorf("'timeouts' must be greater than or equal to 0 on endpoint %s", endpoint.Name)
+	}
+
+	policy.Name = upstreamConnectionPolicyName
+	policy.Configuration = UpstreamConnectionPolicyConfiguration{
+		ConnectTimeout: int(timeouts.ConnectSeconds),
+		SendTimeout:    int(timeouts.SendSeconds),
+		ReadTimeout:    int(timeouts.ReadSeconds),
+	}
+
+	return policy, nil
+}
+
+// processRetries returns the policy sending the failed requests again to the upstream of the Endpoint
+func processRetries(endpoint ostia.Endpoint) (Policy, error) {
+	var policy Policy
+
+	if endpoint.Retries < 1 || endpoint.Retries > maxRetries {
+		return policy, fmt.Errorf("'retries' must be between 1 and %d on endpoint %s", maxRetries, endpoint.Name)
+	}
+
+	policy.Name = retryPolicyName
+	policy.Configuration = RetryPolicyConfiguration{Retries: int(endpoint.Retries)}
+
+	return policy, nil
+}
@@ -0,0 +1,78 @@
+package standalone
+
+import (
+	"encoding/json"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestProcessTimeouts(t *testing.T) {
+	policy, err := processTimeouts(ostia.Endpoint{Name: "orders", Timeouts: &ostia.Timeouts{ConnectSeconds: 2, ReadSeconds: 30}})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	equals(t, upstreamConnectionPolicyName, policy.Name)
+	equals(t, UpstreamConnectionPolicyConfiguration{ConnectTimeout: 2, ReadTimeout: 30}, policy.Configuration)
+
+	if _, err := processTimeouts(ostia.Endpoint{Name: "orders", Timeouts: &ostia.Timeouts{SendSeconds: -1}}); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestCreateConfigTimeouts(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Endpoints: []ostia.Endpoint{
+				{Name: "orders", Host: "https://shop.example.com", Path: "/orders", Timeouts: &ostia.Timeouts{ReadSeconds: 5}, Retries: 2},
+				{Name: "hello", Host: "https://echo-api.3scale.net", Path: "/hello"},
+			},
+		},
+	}
+
+	b, err := CreateConfig(api, Resources{})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	var config Configuration
+	if err = json.Unmarshal(b, &config); err != nil {
+		t.Fatalf("error unmarshalling config - %s", err)
+	}
+
+	// The timeouts are set by a policy, the host is called directly
+	equals(t, []Upstream{}, config.Upstreams)
+	equals(t, "https://shop.example.com", config.Services[0].Upstream)
+
+	chain := config.Services[0].PolicyChain
+	equals(t, upstreamConnectionPolicyName, chain[len(chain)-2].Name)
+	equals(t, retryPolicyName, chain[len(chain)-1].Name)
+}
+
+func TestProcessRetries(t *testing.T) {
+	policy, err := processRetries(ostia.Endpoint{Name: "orders", Retries: 3})
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	equals(t, retryPolicyName, policy.Name)
+	equals(t, RetryPolicyConfiguration{Retries: 3}, policy.Configuration)
+	checkPolicySchema(t, policy)
+
+	if _, err := processRetries(ostia.Endpoint{Name: "orders", Retries: 11}); err == nil {
+		t.Error("expected error for too many retries")
+	}
+}
+
+func TestCreateConfigCircuitBreaker(t *testing.T) {
+	var api = &ostia.API{
+		Spec: ostia.APISpec{
+			Endpoints: []ostia.Endpoint{
+				{Name: "orders", Host: "https://shop.example.com", Path: "/orders", CircuitBreaker: &ostia.CircuitBreaker{FailureThreshold: 5}},
+			},
+		},
+	}
+
+	if _, err := CreateConfig(api, Resources{}); err == nil {
+		t.Error("expected error for the circuit breaker APIcast doesn't support")
+	}
+}
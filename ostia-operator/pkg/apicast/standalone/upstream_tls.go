@@ -0,0 +1,83 @@
+package standalone
+
+import (
+	"encoding/pem"
+	"fmt"
+	"path"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	upstreamMTLSPolicyName = "apicast.policy.upstream_mtls"
+
+	// endpointsDir is where the files referenced by Endpoints are mounted in the APIcast container
+	endpointsDir = "/etc/ostia/endpoints"
+)
+
+// UpstreamClientCertificateDir returns where the client certificate Secret of the Endpoint is mounted
+func UpstreamClientCertificateDir(endpoint string) string {
+	return path.Join(endpointsDir, endpoint, "client")
+}
+
+func processUpstreamTLS(endpoint ostia.Endpoint, resources Resources) (Policy, error) {
+	var policy Policy
+	tls := endpoint.TLS
+
+	if tls.InsecureSkipVerify && tls.CABundleRef != nil {
+		return policy, fmt.Errorf("'caBundleRef' can't be used with 'insecureSkipVerify' on endpoint %s", endpoint.Name)
+	}
+
+	config := UpstreamMTLSPolicyConfiguration{Verify: !tls.InsecureSkipVerify}
+
+	if tls.CABundleRef != nil {
+		bundle, err := configMapValue(tls.CABundleRef, resources)
+		if err != nil {
+			return policy, err
+		}
+		if config.CACertificates, err = splitCertificates(bundle); err != nil {
+			return policy, fmt.Errorf("invalid 'caBundleRef' on endpoint %s: %s", endpoint.Name, err)
+		}
+	}
+
+	if ref := tls.ClientCertificateRef; ref != nil {
+		for _, key := range []string{v1.TLSCertKey, v1.TLSPrivateKeyKey} {
+			if _, err := secretValue(&v1.SecretKeySelector{LocalObjectReference: *ref, Key: key}, resources); err != nil {
+				return policy, err
+			}
+		}
+
+		config.CertificateType = "path"
+		config.Certificate = path.Join(UpstreamClientCertificateDir(endpoint.Name), v1.TLSCertKey)
+		config.CertificateKeyType = "path"
+		config.CertificateKey = path.Join(UpstreamClientCertificateDir(endpoint.Name), v1.TLSPrivateKeyKey)
+	}
+
+	policy.Name = upstreamMTLSPolicyName
+	policy.Configuration = config
+
+	return policy, nil
+}
+
+// splitCertificates returns every PEM encoded certificate of the bundle, the policy takes them one by one
+func splitCertificates(bundle string) ([]string, error) {
+	var certificates []string
+
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			certificates = append(certificates, string(pem.EncodeToMemory(block)))
+		}
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return certificates, nil
+}
@@ -0,0 +1,95 @@
+package standalone
+
+import (
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	rootCA = `-----BEGIN CERTIFICATE-----
+MIIBVDCB+6ADAgECAgEBMAoGCCqGSM49BAMCMBIxEDAOBgNVBAMTB3Jvb3QtY2Ew
+-----END CERTIFICATE-----
+`
+	intermediateCA = `-----BEGIN CERTIFICATE-----
+MIIBWjCCAQGgAwIBAgIBAjAKBggqhkjOPQQDAjASMRAwDgYDVQQDEwdyb290LWNh
+-----END CERTIFICATE-----
+`
+)
+
+func TestProcessUpstreamTLS(t *testing.T) {
+	resources := Resources{
+		Secrets: map[string]*v1.Secret{
+			"gateway-client": {Data: map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")}},
+			"no-key":         {Data: map[string][]byte{"tls.crt": []byte("cert")}},
+		},
+		ConfigMaps: map[string]*v1.ConfigMap{
+			"private-ca": {Data: map[string]string{"bundle.pem": rootCA + intermediateCA, "empty.pem": "not a certificate"}},
+		},
+	}
+	caBundle := func(key string) *v1.ConfigMapKeySelector {
+		return &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "private-ca"}, Key: key}
+	}
+
+	inputs := []struct {
+		tls       *ostia.UpstreamTLS
+		expectErr bool
+		expect    UpstreamMTLSPolicyConfiguration
+	}{
+		{
+			tls:    &ostia.UpstreamTLS{},
+			expect: UpstreamMTLSPolicyConfiguration{Verify: true},
+		},
+		{
+			tls:    &ostia.UpstreamTLS{InsecureSkipVerify: true},
+			expect: UpstreamMTLSPolicyConfiguration{Verify: false},
+		},
+		{
+			tls: &ostia.UpstreamTLS{
+				CABundleRef:          caBundle("bundle.pem"),
+				ClientCertificateRef: &v1.LocalObjectReference{Name: "gateway-client"},
+			},
+			expect: UpstreamMTLSPolicyConfiguration{
+				CertificateType:    "path",
+				Certificate:        "/etc/ostia/endpoints/orders/client/tls.crt",
+				CertificateKeyType: "path",
+				CertificateKey:     "/etc/ostia/endpoints/orders/client/tls.key",
+				Verify:             true,
+				CACertificates:     []string{rootCA, intermediateCA},
+			},
+		},
+		{
+			tls:       &ostia.UpstreamTLS{InsecureSkipVerify: true, CABundleRef: caBundle("bundle.pem")},
+			expectErr: true,
+		},
+		{
+			tls:       &ostia.UpstreamTLS{CABundleRef: caBundle("empty.pem")},
+			expectErr: true,
+		},
+		{
+			tls:       &ostia.UpstreamTLS{CABundleRef: caBundle("missing.pem")},
+			expectErr: true,
+		},
+		{
+			tls:       &ostia.UpstreamTLS{ClientCertificateRef: &v1.LocalObjectReference{Name: "no-key"}},
+			expectErr: true,
+		},
+	}
+
+	for _, input := range inputs {
+		policy, err := processUpstreamTLS(ostia.Endpoint{Name: "orders", TLS: input.tls}, resources)
+		if input.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %#v", input.tls)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("unexpected error - %s", err)
+		}
+
+		equals(t, upstreamMTLSPolicyName, policy.Name)
+		equals(t, input.expect, policy.Configuration)
+		checkPolicySchema(t, policy)
+	}
+}
@@ -0,0 +1,516 @@
+package standalone
+
+import (
+	"fmt"
+	"net/url"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var rateLimitTypes = []string{"FixedWindow", "LeakyBucket", "ConnectionBased"}
+
+// Validate runs the checks done while rendering the APIcast configuration that don't need
+// the referenced resources, so invalid objects can be rejected before being stored
+func Validate(api *ostia.API) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	errs = append(errs, validateRateLimits(spec.Child("rate_limits"), api.Spec.RateLimits, api.Spec.RateLimitStore)...)
+	errs = append(errs, validateAuthentication(spec.Child("authentication"), api.Spec.Authentication)...)
+	errs = append(errs, validateHeaders(spec.Child("headers"), api.Spec.Headers)...)
+	errs = append(errs, validateCORS(spec.Child("cors"), api.Spec.CORS, api.Spec.CORS)...)
+	errs = append(errs, validateIPFilter(spec.Child("ipFilter"), api.Spec.IPFilter)...)
+	errs = append(errs, validatePolicies(spec.Child("policies"), api.Spec.Policies)...)
+	errs = append(errs, validateRateLimitStore(spec.Child("rateLimitStore"), api.Spec.RateLimitStore)...)
+	errs = append(errs, validateRateLimitResponse(spec.Child("rateLimitResponse"), api.Spec.RateLimitResponse)...)
+
+	if tls := api.Spec.TLS; tls != nil {
+		if tls.SecretName == "" {
+			errs = append(errs, field.Required(spec.Child("tls", "secretName"), "secret with the certificate is required"))
+		}
+		if api.Spec.Hostname == "" {
+			errs = append(errs, field.Required(spec.Child("hostname"), "hostname is required to terminate tls"))
+		}
+	}
+
+	names := make(map[string]bool)
+	// APIcast keeps a single cache TTL, the first Endpoint setting it decides
+	var cacheTTLSeconds int32
+	for i, endpoint := range api.Spec.Endpoints {
+		path := spec.Child("endpoints").Index(i)
+
+		if endpoint.Name == "" {
+			errs = append(errs, field.Required(path.Child("name"), "endpoint name is required"))
+		} else if names[endpoint.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), endpoint.Name))
+		}
+		names[endpoint.Name] = true
+
+		switch {
+		case endpoint.BackendRef != nil:
+			if endpoint.Host != "" {
+				errs = append(errs, field.Forbidden(path.Child("host"), "can't be used with backendRef"))
+			}
+			errs = append(errs, validateBackendRef(path.Child("backendRef"), endpoint.BackendRef)...)
+		default:
+			if u, err := url.Parse(endpoint.Host); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, field.Invalid(path.Child("host"), endpoint.Host, "must be an absolute url"))
+			}
+		}
+
+		switch pathMatchOf(endpoint) {
+		case ostia.PathMatchPrefix:
+			if _, err := url.Parse("http://valid.com" + endpoint.Path); err != nil || endpoint.Path == "" || endpoint.Path[0] != '/' {
+				errs = append(errs, field.Invalid(path.Child("path"), endpoint.Path, "must be a valid path starting with /"))
+			}
+		case ostia.PathMatchExact, ostia.PathMatchRegex:
+			errs = append(errs, field.Invalid(path.Child("pathMatch"), endpoint.PathMatch, "not supported by APIcast, its router only matches path prefixes"))
+		default:
+			errs = append(errs, field.NotSupported(path.Child("pathMatch"), endpoint.PathMatch, pathMatchTypes))
+		}
+
+		for j, method := range endpoint.Methods {
+			if !isHTTPMethod(method) {
+				errs = append(errs, field.NotSupported(path.Child("methods").Index(j), method, httpMethods))
+			}
+		}
+
+		errs = append(errs, validateRateLimits(path.Child("rate_limits"), endpoint.RateLimits, api.Spec.RateLimitStore)...)
+		errs = append(errs, validateAuthentication(path.Child("authentication"), endpoint.Authentication)...)
+		errs = append(errs, validateHeaders(path.Child("headers"), endpoint.Headers)...)
+		errs = append(errs, validateIPFilter(path.Child("ipFilter"), endpoint.IPFilter)...)
+		errs = append(errs, validatePolicies(path.Child("policies"), endpoint.Policies)...)
+		errs = append(errs, validateTimeouts(path.Child("timeouts"), endpoint.Timeouts)...)
+		if endpoint.CircuitBreaker != nil {
+			errs = append(errs, field.Forbidden(path.Child("circuitBreaker"),
+				"not supported by APIcast, it has no circuit breaker for upstreams, set timeouts instead"))
+		}
+		if endpoint.Retries < 0 || endpoint.Retries > maxRetries {
+			errs = append(errs, field.Invalid(path.Child("retries"), endpoint.Retries, "must be between 0 and 10"))
+		}
+		if endpoint.Caching != nil {
+			errs = append(errs, validateCaching(path.Child("caching"), endpoint)...)
+
+			if ttl := endpoint.Caching.TTLSeconds; ttl > 0 {
+				if cacheTTLSeconds != 0 && ttl != cacheTTLSeconds {
+					errs = append(errs, field.Invalid(path.Child("caching", "ttlSeconds"), ttl,
+						fmt.Sprintf("must be the %ds of the other endpoints, APIcast has a single cache ttl", cacheTTLSeconds)))
+				} else {
+					cacheTTLSeconds = ttl
+				}
+			}
+		}
+		errs = append(errs, validatePayload(path, endpoint)...)
+		if endpoint.CORS != nil {
+			errs = append(errs, validateCORS(path.Child("cors"), endpoint.CORS, mergeCORS(api.Spec.CORS, endpoint.CORS))...)
+		}
+
+		if endpoint.Rewrite != nil {
+			if _, err := rewriteCommand(endpoint); err != nil {
+				errs = append(errs, field.Invalid(path.Child("rewrite"), endpoint.Rewrite, err.Error()))
+			}
+		}
+
+		if endpoint.TLS != nil {
+			// The endpoint name is used as directory to mount its certificates
+			for _, msg := range validation.IsDNS1123Label(endpoint.Name) {
+				errs = append(errs, field.Invalid(path.Child("name"), endpoint.Name, msg))
+			}
+			if endpoint.TLS.InsecureSkipVerify && endpoint.TLS.CABundleRef != nil {
+				errs = append(errs, field.Forbidden(path.Child("tls", "caBundleRef"), "can't be used with insecureSkipVerify"))
+			}
+		}
+
+		// Invalid scopes are reported above
+		if limits, err := endpointRateLimits(api, endpoint); err == nil {
+			if err := checkLimiterKeys(limits, endpointAuthentication(api, endpoint)); err != nil {
+				errs = append(errs, field.Invalid(path.Child("rate_limits"), endpoint.Name, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateRateLimits(path *field.Path, limits []ostia.RateLimit, store *ostia.RateLimitStore) field.ErrorList {
+	var errs field.ErrorList
+
+	names := make(map[string]bool)
+	for i, limit := range limits {
+		if names[limit.Name] {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("name"), limit.Name))
+		}
+		names[limit.Name] = true
+
+		switch limit.Scope {
+		case "", ostia.RateLimitScopeEndpoint, ostia.RateLimitScopeAPI:
+		case ostia.RateLimitScopeGlobal:
+			if !isSharedStore(store) {
+				errs = append(errs, field.Invalid(path.Index(i).Child("scope"), limit.Scope, "requires a rateLimitStore with an urlSecretRef shared with the other APIs"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(path.Index(i).Child("scope"), limit.Scope, rateLimitScopes))
+		}
+
+		errs = append(errs, validateRateLimit(path.Index(i), limit)...)
+	}
+
+	return errs
+}
+
+func validateRateLimit(path *field.Path, limit ostia.RateLimit) field.ErrorList {
+	var errs field.ErrorList
+
+	switch limit.Type {
+	case "FixedWindow", "LeakyBucket":
+		if _, _, err := parseTimeLimits(limit); err != nil {
+			errs = append(errs, field.Invalid(path.Child("limit"), limit.Limit, err.Error()))
+		}
+	case "ConnectionBased":
+		if limit.Conn == nil {
+			errs = append(errs, field.Required(path.Child("conn"), "conn is required for connection based limits"))
+		} else if *limit.Conn < 1 {
+			errs = append(errs, field.Invalid(path.Child("conn"), *limit.Conn, "must be greater than 0"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("type"), limit.Type, rateLimitTypes))
+	}
+
+	if limit.Source != "" && len(limit.KeySources) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("keySources"), "can't be used with source"))
+	}
+	for i, source := range limit.KeySources {
+		if err := checkKeySource(source); err != nil {
+			errs = append(errs, field.Invalid(path.Child("keySources").Index(i), source, err.Error()))
+		}
+	}
+
+	if limit.Conditions != nil {
+		for i, operation := range limit.Conditions.Operations {
+			if _, err := operation.MarshalAPIcast(); err != nil {
+				errs = append(errs, field.Invalid(path.Child("conditions", "operations").Index(i), operation, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateAuthentication(path *field.Path, auth *ostia.Authentication) field.ErrorList {
+	var errs field.ErrorList
+
+	if auth == nil {
+		return errs
+	}
+
+	if apiKey := auth.APIKey; apiKey != nil {
+		switch apiKey.In {
+		case "", ostia.DefaultAPIKeyLocation, "query":
+			// The name ends up in a liquid template
+			if _, err := apiKeySource(apiKey); err != nil {
+				errs = append(errs, field.Invalid(path.Child("apiKey", "name"), apiKey.Name, err.Error()))
+			}
+		default:
+			errs = append(errs, field.NotSupported(path.Child("apiKey", "in"), apiKey.In, []string{"header", "query"}))
+		}
+		if len(apiKey.SecretRefs) == 0 {
+			errs = append(errs, field.Required(path.Child("apiKey", "secretRefs"), "at least one secret is required"))
+		}
+	}
+
+	if jwt := auth.JWT; jwt != nil {
+		jwtPath := path.Child("jwt")
+
+		if jwt.Issuer == "" {
+			errs = append(errs, field.Required(jwtPath.Child("issuer"), "issuer is required"))
+		} else if err := checkIssuer(jwt.Issuer); err != nil {
+			errs = append(errs, field.Invalid(jwtPath.Child("issuer"), jwt.Issuer, err.Error()))
+		}
+
+		for i, audience := range jwt.Audiences {
+			if err := checkAudience(audience); err != nil {
+				errs = append(errs, field.Invalid(jwtPath.Child("audiences").Index(i), audience, err.Error()))
+			}
+		}
+
+		if jwt.JWKSCacheSeconds < 0 {
+			errs = append(errs, field.Invalid(jwtPath.Child("jwksCacheSeconds"), jwt.JWKSCacheSeconds, "must not be negative"))
+		}
+
+		if jwt.PublicKeyRef != nil {
+			errs = append(errs, field.Forbidden(jwtPath.Child("publicKeyRef"),
+				"static public keys are not supported by APIcast, tokens are verified with the keys published by the issuer"))
+		}
+
+		for _, name := range sortedClaims(jwt.Claims) {
+			if name == "" {
+				errs = append(errs, field.Invalid(jwtPath.Child("claims"), name, "claim name can't be empty"))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateHeaders(path *field.Path, headers *ostia.Headers) field.ErrorList {
+	var errs field.ErrorList
+
+	if headers == nil {
+		return errs
+	}
+
+	errs = append(errs, validateHeaderRules(path.Child("request"), headers.Request)...)
+	errs = append(errs, validateHeaderRules(path.Child("response"), headers.Response)...)
+
+	return errs
+}
+
+func validateHeaderRules(path *field.Path, rules []ostia.HeaderRule) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, rule := range rules {
+		switch rule.Op {
+		case ostia.HeaderAdd, ostia.HeaderSet:
+			if rule.Value == "" {
+				errs = append(errs, field.Required(path.Index(i).Child("value"), "value is required to add or set a header"))
+			}
+		case ostia.HeaderRemove:
+		default:
+			errs = append(errs, field.NotSupported(path.Index(i).Child("op"), rule.Op, headerOps))
+		}
+
+		if !headerName.MatchString(rule.Name) {
+			errs = append(errs, field.Invalid(path.Index(i).Child("name"), rule.Name, "must be a valid header name"))
+		}
+	}
+
+	return errs
+}
+
+// validateCORS checks the fields of cors and that merged, the settings in effect, render into a policy
+func validateCORS(path *field.Path, cors *ostia.CORS, merged *ostia.CORS) field.ErrorList {
+	var errs field.ErrorList
+
+	if cors == nil {
+		return errs
+	}
+
+	for i, origin := range cors.AllowOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, field.Invalid(path.Child("allowOrigins").Index(i), origin, "must be * or an origin like https://example.com"))
+		}
+	}
+
+	for i, method := range cors.AllowMethods {
+		if !isHTTPMethod(method) {
+			errs = append(errs, field.NotSupported(path.Child("allowMethods").Index(i), method, httpMethods))
+		}
+	}
+
+	for i, header := range cors.AllowHeaders {
+		if !headerName.MatchString(header) {
+			errs = append(errs, field.Invalid(path.Child("allowHeaders").Index(i), header, "must be a valid header name"))
+		}
+	}
+
+	if cors.MaxAge != nil && *cors.MaxAge < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxAge"), *cors.MaxAge, "must be greater than or equal to 0"))
+	}
+
+	if _, err := processCORS(merged); err != nil {
+		errs = append(errs, field.Invalid(path, cors, err.Error()))
+	}
+
+	return errs
+}
+
+func validateIPFilter(path *field.Path, filter *ostia.IPFilter) field.ErrorList {
+	var errs field.ErrorList
+
+	if filter == nil {
+		return errs
+	}
+
+	if len(filter.Allow) == 0 && len(filter.Deny) == 0 {
+		errs = append(errs, field.Required(path.Child("allow"), "at least one allowed or denied address is required"))
+	}
+
+	for i, ip := range filter.Allow {
+		if !isIPOrCIDR(ip) {
+			errs = append(errs, field.Invalid(path.Child("allow").Index(i), ip, "must be an IP or CIDR"))
+		}
+	}
+	for i, ip := range filter.Deny {
+		if !isIPOrCIDR(ip) {
+			errs = append(errs, field.Invalid(path.Child("deny").Index(i), ip, "must be an IP or CIDR"))
+		}
+	}
+
+	return errs
+}
+
+func validatePolicies(path *field.Path, policies []ostia.Policy) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, policy := range policies {
+		policyPath := path.Index(i)
+
+		switch policy.Position {
+		case "", ostia.PolicyPositionFirst, ostia.PolicyPositionBeforeRateLimit, ostia.PolicyPositionLast:
+		default:
+			errs = append(errs, field.NotSupported(policyPath.Child("position"), policy.Position, policyPositions))
+		}
+
+		if _, ok := policySchemas[policy.Name]; !ok {
+			errs = append(errs, field.NotSupported(policyPath.Child("name"), policy.Name, bundledPolicies()))
+			continue
+		}
+
+		if policy.Version != "" && policy.Version != ostia.DefaultPolicyVersion {
+			errs = append(errs, field.NotSupported(policyPath.Child("version"), policy.Version, []string{ostia.DefaultPolicyVersion}))
+			continue
+		}
+
+		if config, schemaErrs := policyConfiguration(policy); len(schemaErrs) > 0 {
+			errs = append(errs, field.Invalid(policyPath.Child("configuration"), string(config), joinErrors(schemaErrs)))
+		}
+	}
+
+	return errs
+}
+
+func validateTimeouts(path *field.Path, timeouts *ostia.Timeouts) field.ErrorList {
+	var errs field.ErrorList
+
+	if timeouts == nil {
+		return errs
+	}
+
+	if timeouts.ConnectSeconds < 0 {
+		errs = append(errs, field.Invalid(path.Child("connectSeconds"), timeouts.ConnectSeconds, "must be greater than or equal to 0"))
+	}
+	if timeouts.SendSeconds < 0 {
+		errs = append(errs, field.Invalid(path.Child("sendSeconds"), timeouts.SendSeconds, "must be greater than or equal to 0"))
+	}
+	if timeouts.ReadSeconds < 0 {
+		errs = append(errs, field.Invalid(path.Child("readSeconds"), timeouts.ReadSeconds, "must be greater than or equal to 0"))
+	}
+
+	return errs
+}
+
+func validateCaching(path *field.Path, endpoint ostia.Endpoint) field.ErrorList {
+	var errs field.ErrorList
+	caching := endpoint.Caching
+
+	if !isCacheable(endpoint) {
+		errs = append(errs, field.Forbidden(path, "requires the endpoint to serve GET or HEAD requests"))
+	}
+	if caching.Header != "" && !headerName.MatchString(caching.Header) {
+		errs = append(errs, field.Invalid(path.Child("header"), caching.Header, "must be a valid header name"))
+	}
+	if caching.TTLSeconds < 0 {
+		errs = append(errs, field.Invalid(path.Child("ttlSeconds"), caching.TTLSeconds, "must be greater than or equal to 0"))
+	}
+	if caching.Key != nil {
+		errs = append(errs, field.Forbidden(path.Child("key"), "not supported by APIcast, it builds the cache key from the request URL"))
+	}
+	if caching.MaxSize != nil {
+		errs = append(errs, field.Forbidden(path.Child("maxSize"), "not supported by APIcast, it sets the size of its cache storage itself"))
+	}
+
+	return errs
+}
+
+// validatePayload checks the payload settings, directly under the Endpoint path
+func validatePayload(path *field.Path, endpoint ostia.Endpoint) field.ErrorList {
+	var errs field.ErrorList
+
+	if _, err := bodySize("maxRequestBodySize", endpoint.MaxRequestBodySize); err != nil {
+		errs = append(errs, field.Invalid(path.Child("maxRequestBodySize"), endpoint.MaxRequestBodySize.String(), "must be greater than 0"))
+	}
+	if _, err := bodySize("maxResponseBodySize", endpoint.MaxResponseBodySize); err != nil {
+		errs = append(errs, field.Invalid(path.Child("maxResponseBodySize"), endpoint.MaxResponseBodySize.String(), "must be greater than 0"))
+	}
+
+	if endpoint.RequestSchemaRef != nil {
+		errs = append(errs, field.Forbidden(path.Child("requestSchemaRef"), "not supported by APIcast, it can't validate request bodies"))
+	}
+	if endpoint.PayloadRejectStatus != 0 {
+		errs = append(errs, field.Forbidden(path.Child("payloadRejectStatus"), "not supported by APIcast, requests over maxRequestBodySize get a 413"))
+	}
+
+	return errs
+}
+
+func validateRateLimitStore(path *field.Path, store *ostia.RateLimitStore) field.ErrorList {
+	var errs field.ErrorList
+
+	switch {
+	case store == nil:
+	case store.Managed && store.URLSecretRef != nil:
+		errs = append(errs, field.Forbidden(path.Child("urlSecretRef"), "only one of urlSecretRef and managed can be set"))
+	case store.URLSecretRef != nil:
+		if store.URLSecretRef.Name == "" {
+			errs = append(errs, field.Required(path.Child("urlSecretRef", "name"), "secret name is required"))
+		}
+		if store.URLSecretRef.Key == "" {
+			errs = append(errs, field.Required(path.Child("urlSecretRef", "key"), "secret key is required"))
+		}
+	case !store.Managed:
+		errs = append(errs, field.Required(path, "one of urlSecretRef or managed is required"))
+	}
+
+	return errs
+}
+
+func validateRateLimitResponse(path *field.Path, response *ostia.RateLimitResponse) field.ErrorList {
+	var errs field.ErrorList
+
+	if response == nil {
+		return errs
+	}
+
+	if status := response.Status; status != 0 && (status < 400 || status > 599) {
+		errs = append(errs, field.Invalid(path.Child("status"), status, "must be an http error status"))
+	}
+	if response.Body != "" {
+		errs = append(errs, field.Forbidden(path.Child("body"), "not supported by APIcast, only the status can be changed"))
+	}
+	if response.ContentType != "" {
+		errs = append(errs, field.Forbidden(path.Child("contentType"), "not supported by APIcast, only the status can be changed"))
+	}
+
+	return errs
+}
+
+func validateBackendRef(path *field.Path, ref *ostia.BackendRef) field.ErrorList {
+	var errs field.ErrorList
+
+	for _, msg := range validation.IsDNS1035Label(ref.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), ref.Name, msg))
+	}
+
+	if ref.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(ref.Namespace) {
+			errs = append(errs, field.Invalid(path.Child("namespace"), ref.Namespace, msg))
+		}
+	}
+
+	for _, msg := range validation.IsValidPortNum(int(ref.Port)) {
+		errs = append(errs, field.Invalid(path.Child("port"), ref.Port, msg))
+	}
+
+	switch ref.Scheme {
+	case "", "http", "https":
+	default:
+		errs = append(errs, field.NotSupported(path.Child("scheme"), ref.Scheme, []string{"http", "https"}))
+	}
+
+	return errs
+}
@@ -0,0 +1,185 @@
+package standalone
+
+import (
+	"encoding/json"
+	"testing"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+)
+
+func TestValidate(t *testing.T) {
+	inputs := []struct {
+		spec         []byte
+		expectFields []string
+	}{
+		{
+			spec: []byte(`{"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello"}],
+				"rate_limits":[{"name":"hello","type":"FixedWindow","limit":"10/m"}]}`),
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello"},
+				{"name":"hello","host":"echo-api","path":"hello"}]}`),
+			expectFields: []string{"spec.endpoints[1].name", "spec.endpoints[1].host", "spec.endpoints[1].path"},
+		},
+		{
+			spec: []byte(`{"rate_limits":[{"name":"a","type":"Sliding","limit":"10/m"},{"name":"b","type":"FixedWindow","limit":"10/week"},
+				{"name":"c","type":"ConnectionBased"}]}`),
+			expectFields: []string{"spec.rate_limits[0].type", "spec.rate_limits[1].limit", "spec.rate_limits[2].conn"},
+		},
+		{
+			spec: []byte(`{"rate_limits":[{"name":"a","type":"FixedWindow","limit":"10/m","keySources":[{"clientIP":true},{"jwtClaim":"sub"}]},
+				{"name":"b","type":"FixedWindow","limit":"10/m","source":"{{remote_addr}}","keySources":[{"header":"X-User","queryParam":"user"}]}]}`),
+			expectFields: []string{"spec.rate_limits[1].keySources", "spec.rate_limits[1].keySources[0]"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello",
+				"rate_limits":[{"name":"a","type":"LeakyBucket","limit":"10/m","conditions":{"operation":"and",
+				"operations":[{"http_method":"GET"},{"request_path":"/test","op":"=~"}]}}]}]}`),
+			expectFields: []string{"spec.endpoints[0].rate_limits[0].conditions.operations[1]"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"Hello_World","host":"https://echo-api.3scale.net","path":"/hello",
+				"tls":{"insecureSkipVerify":true,"caBundleRef":{"name":"ca","key":"ca.crt"}}}]}`),
+			expectFields: []string{"spec.endpoints[0].name", "spec.endpoints[0].tls.caBundleRef"},
+		},
+		{
+			spec:         []byte(`{"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello","methods":["get","FETCH"]}]}`),
+			expectFields: []string{"spec.endpoints[0].methods[1]"},
+		},
+		{
+			// APIcast's router only compares the path with uri_path
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://echo-api.3scale.net","path":"/a","pathMatch":"prefix"},
+				{"name":"b","host":"https://echo-api.3scale.net","path":"^/v[0-9]+/","pathMatch":"regex"},
+				{"name":"c","host":"https://echo-api.3scale.net","path":"/c","pathMatch":"exact"},
+				{"name":"d","host":"https://echo-api.3scale.net","path":"/d","pathMatch":"glob"}]}`),
+			expectFields: []string{"spec.endpoints[1].pathMatch", "spec.endpoints[2].pathMatch", "spec.endpoints[3].pathMatch"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://echo-api.3scale.net","path":"/a","rewrite":{"stripPrefix":true}},
+				{"name":"b","host":"https://echo-api.3scale.net","path":"/b","rewrite":{"stripPrefix":true,"replacePrefix":"/c"}}]}`),
+			expectFields: []string{"spec.endpoints[1].rewrite"},
+		},
+		{
+			spec: []byte(`{"headers":{"request":[{"op":"set","name":"X-Tenant","value":"acme"},{"op":"add","name":"X-Trace"}],
+				"response":[{"op":"remove","name":"X Internal"},{"op":"replace","name":"Server","value":"ostia"}]}}`),
+			expectFields: []string{"spec.headers.request[1].value", "spec.headers.response[0].name", "spec.headers.response[1].op"},
+		},
+		{
+			spec: []byte(`{"cors":{"allowOrigins":["*"],"allowMethods":["GET","FETCH"],"maxAge":-1},
+				"endpoints":[{"name":"a","host":"https://echo-api.3scale.net","path":"/a","cors":{"allowCredentials":true}},
+				{"name":"b","host":"https://echo-api.3scale.net","path":"/b","cors":{"allowOrigins":["example.com"],"allowCredentials":true}}]}`),
+			expectFields: []string{"spec.cors.allowMethods[1]", "spec.cors.maxAge", "spec.endpoints[0].cors",
+				"spec.endpoints[1].cors.allowOrigins[0]"},
+		},
+		{
+			spec: []byte(`{"ipFilter":{"allow":["10.0.0.0/8","192.168.1.300"],"deny":["10.0.0.1","fd00::/8"]},
+				"endpoints":[{"name":"admin","host":"https://echo-api.3scale.net","path":"/admin","ipFilter":{"trustForwardedFor":true}}]}`),
+			expectFields: []string{"spec.ipFilter.allow[1]", "spec.endpoints[0].ipFilter.allow"},
+		},
+		{
+			spec: []byte(`{"policies":[{"name":"apicast.policy.retry","configuration":{"retries":3}},
+				{"name":"apicast.policy.soap"},{"name":"apicast.policy.echo","configuration":{"status":"ok"},"position":"middle"}]}`),
+			expectFields: []string{"spec.policies[1].name", "spec.policies[2].position", "spec.policies[2].configuration"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","path":"/a","backendRef":{"name":"orders","namespace":"shop","port":8080}},
+				{"name":"b","host":"https://b.example.com","path":"/b","backendRef":{"name":"Orders","port":0,"scheme":"tcp"}}]}`),
+			expectFields: []string{"spec.endpoints[1].host", "spec.endpoints[1].backendRef.name",
+				"spec.endpoints[1].backendRef.port", "spec.endpoints[1].backendRef.scheme"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a",
+				"timeouts":{"connectSeconds":-1,"readSeconds":30},"circuitBreaker":{"failureThreshold":5,"openSeconds":30}}]}`),
+			expectFields: []string{"spec.endpoints[0].timeouts.connectSeconds", "spec.endpoints[0].circuitBreaker"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a","retries":3},
+				{"name":"b","host":"https://b.example.com","path":"/b","retries":11}]}`),
+			expectFields: []string{"spec.endpoints[1].retries"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a","methods":["POST"],
+				"caching":{"header":"bad header","ttlSeconds":-1}}]}`),
+			expectFields: []string{"spec.endpoints[0].caching", "spec.endpoints[0].caching.header", "spec.endpoints[0].caching.ttlSeconds"},
+		},
+		{
+			// APIcast has a single cache ttl, and no settings for the cache key or size
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a","caching":{"ttlSeconds":60}},
+				{"name":"b","host":"https://b.example.com","path":"/b","caching":{"ttlSeconds":300}},
+				{"name":"c","host":"https://c.example.com","path":"/c","caching":{"ttlSeconds":60,"key":{"headers":["Accept"]},"maxSize":"1Gi"}}]}`),
+			expectFields: []string{"spec.endpoints[1].caching.ttlSeconds", "spec.endpoints[2].caching.key", "spec.endpoints[2].caching.maxSize"},
+		},
+		{
+			spec: []byte(`{"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a","maxRequestBodySize":"1Mi",
+				"maxResponseBodySize":"-1","requestSchemaRef":{"name":"schemas","key":"order.json"},"payloadRejectStatus":400}]}`),
+			expectFields: []string{"spec.endpoints[0].maxResponseBodySize", "spec.endpoints[0].requestSchemaRef", "spec.endpoints[0].payloadRejectStatus"},
+		},
+		{
+			spec:         []byte(`{"rateLimitStore":{"managed":true,"urlSecretRef":{"name":"redis","key":"url"}}}`),
+			expectFields: []string{"spec.rateLimitStore.urlSecretRef"},
+		},
+		{
+			spec:         []byte(`{"rateLimitStore":{"urlSecretRef":{"name":"redis"}}}`),
+			expectFields: []string{"spec.rateLimitStore.urlSecretRef.key"},
+		},
+		{
+			spec: []byte(`{"rate_limits":[{"name":"a","type":"FixedWindow","limit":"10/m","scope":"global"},
+				{"name":"a","type":"FixedWindow","limit":"10/m","scope":"cluster"}]}`),
+			expectFields: []string{"spec.rate_limits[0].scope", "spec.rate_limits[1].name", "spec.rate_limits[1].scope"},
+		},
+		{
+			spec:         []byte(`{"rateLimitStore":{"managed":true},"rate_limits":[{"name":"a","type":"FixedWindow","limit":"10/m","scope":"global"}]}`),
+			expectFields: []string{"spec.rate_limits[0].scope"},
+		},
+		{
+			spec: []byte(`{"rate_limits":[{"name":"per-ip","type":"FixedWindow","limit":"10/s","source":"{{remote_addr}}","scope":"endpoint"}],
+				"endpoints":[{"name":"a","host":"https://a.example.com","path":"/a",
+				"rate_limits":[{"name":"burst","type":"LeakyBucket","limit":"1/s","source":"{{remote_addr}}"}]}]}`),
+			expectFields: []string{"spec.endpoints[0].rate_limits"},
+		},
+		{
+			spec:         []byte(`{"rateLimitResponse":{"status":302,"body":"slow down","contentType":"text/plain"}}`),
+			expectFields: []string{"spec.rateLimitResponse.status", "spec.rateLimitResponse.body", "spec.rateLimitResponse.contentType"},
+		},
+		{
+			spec:         []byte(`{"tls":{"listener":true}}`),
+			expectFields: []string{"spec.tls.secretName", "spec.hostname"},
+		},
+		{
+			spec: []byte(`{"authentication":{"apiKey":{"in":"cookie"},"jwt":{"issuer":"sso.example.com"}}}`),
+			expectFields: []string{"spec.authentication.apiKey.in", "spec.authentication.apiKey.secretRefs",
+				"spec.authentication.jwt.issuer"},
+		},
+		{
+			spec: []byte(`{"authentication":{"apiKey":{"in":"query","name":"user-key","secretRefs":[{"name":"keys"}]}},
+				"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello",
+				"authentication":{"apiKey":{"name":"X-Key']}}","secretRefs":[{"name":"keys"}]}}}]}`),
+			expectFields: []string{"spec.authentication.apiKey.name", "spec.endpoints[0].authentication.apiKey.name"},
+		},
+		{
+			spec: []byte(`{"authentication":{"jwt":{"issuer":"https://sso.example.com","audiences":["orders",""],
+				"jwksCacheSeconds":-1,"publicKeyRef":{"name":"keys","key":"key.pem"}}}}`),
+			expectFields: []string{"spec.authentication.jwt.audiences[1]", "spec.authentication.jwt.jwksCacheSeconds",
+				"spec.authentication.jwt.publicKeyRef"},
+		},
+		{
+			spec:         []byte(`{"rate_limits":[{"name":"a","type":"FixedWindow","limit":"10/m","keySources":[{"header":"X-Tenant']}}"}]}]}`),
+			expectFields: []string{"spec.rate_limits[0].keySources[0]"},
+		},
+	}
+
+	for _, input := range inputs {
+		api := &ostia.API{}
+		if err := json.Unmarshal(input.spec, &api.Spec); err != nil {
+			t.Fatalf("error unmarshalling spec - %s", err)
+		}
+
+		errs := Validate(api)
+
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		equals(t, input.expectFields, fields)
+	}
+}
@@ -0,0 +1,226 @@
+package apicast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// generationAnnotation records on the Deployment the API generation its configuration was rendered from
+const generationAnnotation = "ostia.3scale.net/api-generation"
+
+// setCondition adds or replaces the condition of the same type,
+// LastTransitionTime is only moved forward when the status changes
+func setCondition(status *ostia.APIStatus, condition ostia.APICondition, now metav1.Time) {
+	condition.LastTransitionTime = now
+
+	for i, existing := range status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		status.Conditions[i] = condition
+		return
+	}
+
+	status.Conditions = append(status.Conditions, condition)
+}
+
+func removeCondition(status *ostia.APIStatus, conditionType ostia.APIConditionType) {
+	var conditions []ostia.APICondition
+
+	for _, condition := range status.Conditions {
+		if condition.Type != conditionType {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	status.Conditions = conditions
+}
+
+func getCondition(status ostia.APIStatus, conditionType ostia.APIConditionType) *ostia.APICondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isConditionTrue(status ostia.APIStatus, conditionType ostia.APIConditionType) bool {
+	condition := getCondition(status, conditionType)
+	return condition != nil && condition.Status == v1.ConditionTrue
+}
+
+// reasonFetchReferencesFailed tells the objects referenced by the API couldn't be read, so its configuration is unknown
+const reasonFetchReferencesFailed = "FetchReferencesFailed"
+
+// reconcileFailure is a step of the reconciliation the API server failed, Reason is reported in the Ready condition
+type reconcileFailure struct {
+	reason string
+	err    error
+}
+
+func configValidCondition(configErr error, failures []reconcileFailure) ostia.APICondition {
+	for _, failure := range failures {
+		if failure.reason == reasonFetchReferencesFailed {
+			return ostia.APICondition{
+				Type:    ostia.ConditionConfigValid,
+				Status:  v1.ConditionUnknown,
+				Reason:  failure.reason,
+				Message: failure.err.Error(),
+			}
+		}
+	}
+
+	if configErr != nil {
+		return ostia.APICondition{
+			Type:    ostia.ConditionConfigValid,
+			Status:  v1.ConditionFalse,
+			Reason:  "InvalidConfiguration",
+			Message: configErr.Error(),
+		}
+	}
+
+	return ostia.APICondition{
+		Type:    ostia.ConditionConfigValid,
+		Status:  v1.ConditionTrue,
+		Reason:  "ConfigurationRendered",
+		Message: "APIcast configuration was generated successfully",
+	}
+}
+
+// deploymentAvailableCondition follows the same rules as `kubectl rollout status`,
+// and also requires the Deployment to run the configuration of the given API generation
+func deploymentAvailableCondition(deployment *appsv1.Deployment, generation int64) ostia.APICondition {
+	condition := ostia.APICondition{Type: ostia.ConditionDeploymentAvailable, Status: v1.ConditionFalse}
+
+	if deployment == nil {
+		condition.Reason = "DeploymentNotFound"
+		condition.Message = "APIcast Deployment does not exist yet"
+		return condition
+	}
+
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			condition.Reason = c.Reason
+			condition.Message = c.Message
+			return condition
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Annotations[generationAnnotation] != strconv.FormatInt(generation, 10):
+		condition.Reason = "RolloutPending"
+		condition.Message = fmt.Sprintf("Deployment does not contain the configuration of generation %d yet", generation)
+	case deployment.Status.ObservedGeneration < deployment.Generation:
+		condition.Reason = "RolloutPending"
+		condition.Message = "Waiting for the Deployment spec update to be observed"
+	case deployment.Status.UpdatedReplicas < replicas:
+		condition.Reason = "RolloutInProgress"
+		condition.Message = fmt.Sprintf("%d out of %d new replicas have been updated", deployment.Status.UpdatedReplicas, replicas)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		condition.Reason = "RolloutInProgress"
+		condition.Message = fmt.Sprintf("%d old replicas are pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		condition.Reason = "MinimumReplicasUnavailable"
+		condition.Message = fmt.Sprintf("%d of %d updated replicas are available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	default:
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "RolloutComplete"
+		condition.Message = fmt.Sprintf("%d replicas are available", deployment.Status.AvailableReplicas)
+	}
+
+	return condition
+}
+
+func ingressAdmittedCondition(ingress *extensions.Ingress) ostia.APICondition {
+	condition := ostia.APICondition{Type: ostia.ConditionIngressAdmitted, Status: v1.ConditionFalse}
+
+	if ingress == nil {
+		condition.Reason = "IngressNotFound"
+		condition.Message = "APIcast Ingress does not exist yet"
+		return condition
+	}
+
+	var addresses []string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		} else if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+	}
+
+	if len(addresses) == 0 {
+		condition.Reason = "AddressPending"
+		condition.Message = "Ingress has not been assigned an address by the ingress controller"
+		return condition
+	}
+
+	condition.Status = v1.ConditionTrue
+	condition.Reason = "Admitted"
+	condition.Message = fmt.Sprintf("Ingress is served at %s", strings.Join(addresses, ", "))
+
+	return condition
+}
+
+func readyCondition(status ostia.APIStatus, exposed bool, failures []reconcileFailure) ostia.APICondition {
+	if len(failures) > 0 {
+		var messages []string
+		for _, failure := range failures {
+			messages = append(messages, fmt.Sprintf("%s: %s", failure.reason, failure.err))
+		}
+
+		return ostia.APICondition{
+			Type:    ostia.ConditionReady,
+			Status:  v1.ConditionFalse,
+			Reason:  failures[0].reason,
+			Message: strings.Join(messages, "; "),
+		}
+	}
+
+	required := []ostia.APIConditionType{ostia.ConditionConfigValid, ostia.ConditionDeploymentAvailable}
+	if exposed {
+		required = append(required, ostia.ConditionIngressAdmitted)
+	}
+	if getCondition(status, ostia.ConditionBackendsResolved) != nil {
+		required = append(required, ostia.ConditionBackendsResolved)
+	}
+
+	var pending []string
+	for _, conditionType := range required {
+		if !isConditionTrue(status, conditionType) {
+			pending = append(pending, string(conditionType))
+		}
+	}
+
+	if len(pending) > 0 {
+		return ostia.APICondition{
+			Type:    ostia.ConditionReady,
+			Status:  v1.ConditionFalse,
+			Reason:  "NotReady",
+			Message: fmt.Sprintf("Waiting for %s", strings.Join(pending, ", ")),
+		}
+	}
+
+	return ostia.APICondition{
+		Type:    ostia.ConditionReady,
+		Status:  v1.ConditionTrue,
+		Reason:  "Ready",
+		Message: "API is being served",
+	}
+}
@@ -0,0 +1,120 @@
+package apicast
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	ostia "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	before := metav1.NewTime(time.Unix(1000, 0))
+	now := metav1.NewTime(time.Unix(2000, 0))
+
+	status := ostia.APIStatus{Conditions: []ostia.APICondition{
+		{Type: ostia.ConditionConfigValid, Status: v1.ConditionTrue, LastTransitionTime: before},
+		{Type: ostia.ConditionReady, Status: v1.ConditionFalse, LastTransitionTime: before},
+	}}
+
+	setCondition(&status, ostia.APICondition{Type: ostia.ConditionConfigValid, Status: v1.ConditionTrue, Reason: "Same"}, now)
+	setCondition(&status, ostia.APICondition{Type: ostia.ConditionReady, Status: v1.ConditionTrue}, now)
+	setCondition(&status, ostia.APICondition{Type: ostia.ConditionDeploymentAvailable, Status: v1.ConditionTrue}, now)
+
+	if c := getCondition(status, ostia.ConditionConfigValid); c.LastTransitionTime != before || c.Reason != "Same" {
+		t.Errorf("unchanged status should keep transition time and update reason - %#v", c)
+	}
+	if c := getCondition(status, ostia.ConditionReady); c.LastTransitionTime != now {
+		t.Errorf("changed status should update transition time - %#v", c)
+	}
+	if c := getCondition(status, ostia.ConditionDeploymentAvailable); c == nil || c.LastTransitionTime != now {
+		t.Errorf("new condition should be added - %#v", c)
+	}
+
+	removeCondition(&status, ostia.ConditionReady)
+	if len(status.Conditions) != 2 || getCondition(status, ostia.ConditionReady) != nil {
+		t.Errorf("condition was not removed - %#v", status.Conditions)
+	}
+}
+
+func TestDeploymentAvailableCondition(t *testing.T) {
+	replicas := int32(2)
+	deployment := func(generation int64, apiGeneration string, status appsv1.DeploymentStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Generation:  generation,
+				Annotations: map[string]string{generationAnnotation: apiGeneration},
+			},
+			Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: status,
+		}
+	}
+
+	inputs := []struct {
+		deployment   *appsv1.Deployment
+		expectStatus v1.ConditionStatus
+		expectReason string
+	}{
+		{nil, v1.ConditionFalse, "DeploymentNotFound"},
+		{deployment(1, "1", appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			v1.ConditionFalse, "RolloutPending"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			v1.ConditionFalse, "RolloutPending"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 2}),
+			v1.ConditionFalse, "RolloutInProgress"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			v1.ConditionFalse, "RolloutInProgress"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 0}),
+			v1.ConditionFalse, "MinimumReplicasUnavailable"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 0,
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"}}}),
+			v1.ConditionFalse, "ProgressDeadlineExceeded"},
+		{deployment(2, "3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			v1.ConditionTrue, "RolloutComplete"},
+	}
+
+	for _, input := range inputs {
+		condition := deploymentAvailableCondition(input.deployment, 3)
+		if condition.Status != input.expectStatus || condition.Reason != input.expectReason {
+			t.Errorf("expected %s/%s, got %s/%s - %s", input.expectStatus, input.expectReason,
+				condition.Status, condition.Reason, condition.Message)
+		}
+	}
+}
+
+func TestReadyCondition(t *testing.T) {
+	now := metav1.Now()
+	status := ostia.APIStatus{}
+
+	setCondition(&status, configValidCondition(errors.New("bad limit"), nil), now)
+	setCondition(&status, ostia.APICondition{Type: ostia.ConditionDeploymentAvailable, Status: v1.ConditionTrue}, now)
+	if c := readyCondition(status, false, nil); c.Status != v1.ConditionFalse || c.Message != "Waiting for ConfigValid" {
+		t.Errorf("api with invalid config should not be ready - %#v", c)
+	}
+
+	setCondition(&status, configValidCondition(nil, nil), now)
+	if c := readyCondition(status, false, nil); c.Status != v1.ConditionTrue {
+		t.Errorf("api not exposed should not wait for the ingress - %#v", c)
+	}
+
+	setCondition(&status, ingressAdmittedCondition(&extensions.Ingress{}), now)
+	if c := readyCondition(status, true, nil); c.Status != v1.ConditionFalse || c.Message != "Waiting for IngressAdmitted" {
+		t.Errorf("exposed api should wait for the ingress - %#v", c)
+	}
+
+	ingress := &extensions.Ingress{}
+	ingress.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+	setCondition(&status, ingressAdmittedCondition(ingress), now)
+	if c := readyCondition(status, true, nil); c.Status != v1.ConditionTrue {
+		t.Errorf("exposed api should be ready - %#v", c)
+	}
+
+	setCondition(&status, ostia.APICondition{Type: ostia.ConditionBackendsResolved, Status: v1.ConditionFalse}, now)
+	if c := readyCondition(status, true, nil); c.Status != v1.ConditionFalse || c.Message != "Waiting for BackendsResolved" {
+		t.Errorf("api with missing backends should not be ready - %#v", c)
+	}
+}
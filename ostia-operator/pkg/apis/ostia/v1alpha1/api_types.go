@@ -2,7 +2,9 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -18,10 +20,64 @@ type APISpec struct {
 	// +patchMergeKey=name
 	// +patchStrategy=merge,retainKeys
 	RateLimits []RateLimit `json:"rate_limits,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// Authentication applies to every Endpoint not defining its own
+	Authentication *Authentication `json:"authentication,omitempty"`
+	TLS            *GatewayTLS     `json:"tls,omitempty"`
+	// Headers apply to every Endpoint, before the ones of the Endpoint
+	Headers *Headers `json:"headers,omitempty"`
+	// CORS applies to every Endpoint, the fields set on an Endpoint take precedence
+	CORS *CORS `json:"cors,omitempty"`
+	// IPFilter applies to every Endpoint not defining its own
+	IPFilter *IPFilter `json:"ipFilter,omitempty"`
+	// Policies are added to the chain of every Endpoint, before the ones of the Endpoint
+	Policies []Policy `json:"policies,omitempty"`
+	// RateLimitStore shares the rate limit counters among the APIcast replicas, otherwise every replica counts apart
+	RateLimitStore *RateLimitStore `json:"rateLimitStore,omitempty"`
+	// RateLimitResponse applies to every rate limit of the API and its Endpoints
+	RateLimitResponse *RateLimitResponse `json:"rateLimitResponse,omitempty"`
+}
+
+// RateLimitResponse is what the requests over a rate limit are answered with, instead of the APIcast default error.
+// APIcast can't add headers telling the state of the rate limit counters to the responses.
+type RateLimitResponse struct {
+	Status int32 `json:"status,omitempty"` // Defaults to 429
+	// Body and ContentType are rejected, APIcast keeps its own body, only the status can be changed
+	Body        string `json:"body,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// RateLimitStore is the Redis keeping the rate limit counters, set either URLSecretRef or Managed
+type RateLimitStore struct {
+	// URLSecretRef holds the URL of an existing Redis, like redis://:password@redis.example.com:6379/1.
+	// The URL is only copied to the configuration Secret of the API, never to its Deployment.
+	URLSecretRef *corev1.SecretKeySelector `json:"urlSecretRef,omitempty"`
+	// Managed deploys a Redis owned by the API, the counters are lost when it restarts
+	Managed bool `json:"managed,omitempty"`
+}
+
+// GatewayTLS terminates TLS for Hostname with the certificate of a kubernetes.io/tls Secret,
+// like the ones issued by cert-manager
+type GatewayTLS struct {
+	SecretName string `json:"secretName"`
+	// Listener makes APIcast serve HTTPS itself with the same certificate, besides the Ingress
+	Listener bool `json:"listener,omitempty"`
 }
 
 type APIConditionType string
 
+const (
+	// ConditionConfigValid is true when the API spec renders into a valid APIcast configuration
+	ConditionConfigValid APIConditionType = "ConfigValid"
+	// ConditionDeploymentAvailable is true when the APIcast Deployment finished rolling out the current configuration
+	ConditionDeploymentAvailable APIConditionType = "DeploymentAvailable"
+	// ConditionIngressAdmitted is true when the Ingress of an exposed API got an address assigned
+	ConditionIngressAdmitted APIConditionType = "IngressAdmitted"
+	// ConditionBackendsResolved is true when every Service referenced by a BackendRef exists and has the port
+	ConditionBackendsResolved APIConditionType = "BackendsResolved"
+	// ConditionReady is true when all the other conditions are true for the observed generation
+	ConditionReady APIConditionType = "Ready"
+)
+
 // APIStatus Contains the Status of the API object
 type APIStatus struct { //TODO: Make this struct not user editable
 	Deployed bool `json:"deployed"`
@@ -55,10 +111,208 @@ type APICondition struct {
 
 // Endpoint is a struct used to define the different upstream services
 type Endpoint struct {
-	Name       string      `json:"name"` // Not really needed?
-	Host       string      `json:"host"`
-	Path       string      `json:"path"`
-	RateLimits []RateLimit `json:"rate_limits,omitempty"`
+	Name           string          `json:"name"` // Unique within the API, names the APIcast service of the Endpoint
+	Host           string          `json:"host"`
+	Path           string          `json:"path"`
+	PathMatch      PathMatchType   `json:"pathMatch,omitempty"`
+	Methods        []string        `json:"methods,omitempty"`  // Only match requests with these methods, others get 405
+	Hostname       string          `json:"hostname,omitempty"` // Only match requests for this Host header
+	RateLimits     []RateLimit     `json:"rate_limits,omitempty"`
+	Authentication *Authentication `json:"authentication,omitempty"`
+	TLS            *UpstreamTLS    `json:"tls,omitempty"`
+	Rewrite        *Rewrite        `json:"rewrite,omitempty"` // Changes the request path before calling the host
+	Headers        *Headers        `json:"headers,omitempty"`
+	CORS           *CORS           `json:"cors,omitempty"`
+	IPFilter       *IPFilter       `json:"ipFilter,omitempty"`
+	Policies       []Policy        `json:"policies,omitempty"`
+	// BackendRef calls a Service of the cluster, Host must be empty when set
+	BackendRef *BackendRef `json:"backendRef,omitempty"`
+	Timeouts   *Timeouts   `json:"timeouts,omitempty"`
+	// Retries is how many more times a request is sent to the host when it fails, at most 10
+	Retries int32 `json:"retries,omitempty"`
+	// CircuitBreaker is rejected, APIcast has no circuit breaker, Timeouts keep slow hosts from tying up the gateway
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+	Caching        *Caching        `json:"caching,omitempty"`
+	// MaxRequestBodySize rejects larger requests with 413, without calling the upstream
+	MaxRequestBodySize *resource.Quantity `json:"maxRequestBodySize,omitempty"`
+	// MaxResponseBodySize answers an error instead of the larger responses of the upstream
+	MaxResponseBodySize *resource.Quantity `json:"maxResponseBodySize,omitempty"`
+	// RequestSchemaRef is rejected, APIcast has no policy validating request bodies against a JSON Schema
+	RequestSchemaRef *corev1.ConfigMapKeySelector `json:"requestSchemaRef,omitempty"`
+	// PayloadRejectStatus is rejected, the payload_limits policy of APIcast always answers 413
+	PayloadRejectStatus int32 `json:"payloadRejectStatus,omitempty"`
+}
+
+// PathMatchType defines how the Endpoint Path is compared with the request path
+type PathMatchType string
+
+const (
+	// PathMatchPrefix matches the request paths starting with the Endpoint path
+	PathMatchPrefix PathMatchType = "prefix"
+	// PathMatchExact is rejected, APIcast's router can't match a path exactly
+	PathMatchExact PathMatchType = "exact"
+	// PathMatchRegex is rejected, APIcast's router can't match a path against a regular expression
+	PathMatchRegex PathMatchType = "regex"
+)
+
+// Rewrite changes the path of the requests proxied to the Endpoint host, only one option can be set
+type Rewrite struct {
+	StripPrefix   bool          `json:"stripPrefix,omitempty"`   // Removes the Endpoint path, /hello/world becomes /world
+	ReplacePrefix string        `json:"replacePrefix,omitempty"` // Replaces the Endpoint path, /hello/world becomes <replacePrefix>/world
+	Regex         *RegexRewrite `json:"regex,omitempty"`
+}
+
+// RegexRewrite replaces the first match of Pattern in the request path with Replacement,
+// which can reference the capture groups as $1, $2...
+type RegexRewrite struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// Headers changes the headers of the requests proxied to the host and of the responses returned to the client
+type Headers struct {
+	Request  []HeaderRule `json:"request,omitempty"`
+	Response []HeaderRule `json:"response,omitempty"`
+}
+
+// HeaderRule changes a single header, rules are applied in order
+type HeaderRule struct {
+	Op   HeaderOp `json:"op"`
+	Name string   `json:"name"`
+	// Value can be a liquid template, like {{ headers['X-Tenant'] }} or {{ ngx.var.request_id }}
+	Value string `json:"value,omitempty"`
+}
+
+// HeaderOp is what a HeaderRule does to the header
+type HeaderOp string
+
+const (
+	// HeaderAdd appends the value to the header, creating it when missing
+	HeaderAdd HeaderOp = "add"
+	// HeaderSet replaces any value of the header
+	HeaderSet HeaderOp = "set"
+	// HeaderRemove deletes the header
+	HeaderRemove HeaderOp = "remove"
+)
+
+// CORS lets browsers call the API from pages served by other origins, preflight requests are answered by the gateway
+type CORS struct {
+	AllowOrigins     []string `json:"allowOrigins,omitempty"` // Use * to allow any origin
+	AllowMethods     []string `json:"allowMethods,omitempty"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty"`
+	AllowCredentials *bool    `json:"allowCredentials,omitempty"`
+	MaxAge           *int32   `json:"maxAge,omitempty"` // Seconds browsers can cache the preflight response
+}
+
+// IPFilter rejects with 403 the requests from addresses not allowed, Deny is checked before Allow
+type IPFilter struct {
+	Allow []string `json:"allow,omitempty"` // IPs or CIDRs, when set any other address is rejected
+	Deny  []string `json:"deny,omitempty"`  // IPs or CIDRs
+	// TrustForwardedFor takes the client address from the X-Forwarded-For header, set it when behind a trusted proxy
+	TrustForwardedFor bool `json:"trustForwardedFor,omitempty"`
+}
+
+// Policy adds an APIcast policy to the chain as is, for the features without their own fields.
+// Only the builtin policies whose schema is bundled in the operator are allowed. The bundled schemas are a subset
+// of the APIcast ones and the checks are partial, so APIcast can still reject a configuration accepted here.
+type Policy struct {
+	Name          string                `json:"name"`              // Like apicast.policy.retry
+	Version       string                `json:"version,omitempty"` // Defaults to builtin
+	Configuration *runtime.RawExtension `json:"configuration,omitempty"`
+	Position      PolicyPosition        `json:"position,omitempty"`
+}
+
+// PolicyPosition is where a Policy goes in the chain, relative to the policies generated from the other fields
+type PolicyPosition string
+
+const (
+	// PolicyPositionFirst runs the Policy before any other, even before the ip filter
+	PolicyPositionFirst PolicyPosition = "first"
+	// PolicyPositionBeforeRateLimit runs the Policy after authentication and before the rate limits
+	PolicyPositionBeforeRateLimit PolicyPosition = "beforeRateLimit"
+	// PolicyPositionLast runs the Policy after every other one, right before calling the host
+	PolicyPositionLast PolicyPosition = "last"
+)
+
+// BackendRef points to a port of a Service, called through its cluster DNS name.
+// The Service balances the requests among its ready pods, APIcast doesn't balance upstreams itself.
+type BackendRef struct {
+	Name string `json:"name"`
+	// Namespace defaults to the one of the API. Other namespaces must be watched by the operator,
+	// and using them requires permission to get Services there.
+	Namespace string `json:"namespace,omitempty"`
+	Port      int32  `json:"port"`
+	Scheme    string `json:"scheme,omitempty"` // Either http or https, defaults to http
+}
+
+// Timeouts limits how long the gateway waits on the upstream of the Endpoint, the APIcast defaults apply when not set
+type Timeouts struct {
+	ConnectSeconds int32 `json:"connectSeconds,omitempty"` // To establish the connection
+	SendSeconds    int32 `json:"sendSeconds,omitempty"`    // Between two writes of the request
+	ReadSeconds    int32 `json:"readSeconds,omitempty"`    // Between two reads of the response
+}
+
+// CircuitBreaker would stop calling the host for OpenSeconds after FailureThreshold consecutive failed requests,
+// then let HalfOpenRequests through and resume calling it once they succeed
+type CircuitBreaker struct {
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	OpenSeconds      int32 `json:"openSeconds,omitempty"`
+	HalfOpenRequests int32 `json:"halfOpenRequests,omitempty"`
+}
+
+// Caching stores the responses to GET and HEAD requests of the Endpoint and answers with them while fresh,
+// as told by their Cache-Control header or for TTLSeconds
+type Caching struct {
+	Header string `json:"header,omitempty"` // Response header telling if the cache was hit, defaults to X-Cache-Status
+	// TTLSeconds is how long responses are fresh when Cache-Control doesn't tell, the APIcast default of one minute
+	// applies when not set. APIcast keeps a single one, so the Endpoints of an API setting it must agree.
+	TTLSeconds int32 `json:"ttlSeconds,omitempty"`
+	// Key is rejected, APIcast builds the cache key from the request URL and it can't be changed
+	Key *CacheKey `json:"key,omitempty"`
+	// MaxSize is rejected, APIcast sets the size of its cache storage itself
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+}
+
+// CacheKey would tell apart the cached responses, the request path is always part of it
+type CacheKey struct {
+	IgnoreQuery bool     `json:"ignoreQuery,omitempty"` // Same response for any query string
+	Headers     []string `json:"headers,omitempty"`     // Request headers the responses vary on, like Accept
+}
+
+// UpstreamTLS configures the TLS connection from the gateway to the Endpoint host
+type UpstreamTLS struct {
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CABundleRef holds the PEM encoded certificates used to verify the host, instead of the ones trusted by APIcast
+	CABundleRef *corev1.ConfigMapKeySelector `json:"caBundleRef,omitempty"`
+	// ClientCertificateRef is a kubernetes.io/tls Secret presented to the host for mutual TLS
+	ClientCertificateRef *corev1.LocalObjectReference `json:"clientCertificateRef,omitempty"`
+}
+
+// Authentication defines how clients must identify themselves to reach an Endpoint
+type Authentication struct {
+	APIKey *APIKeyAuthentication `json:"apiKey,omitempty"`
+	JWT    *JWTAuthentication    `json:"jwt,omitempty"`
+}
+
+// APIKeyAuthentication accepts requests carrying one of the keys stored in the referenced Secrets.
+// Every value of every referenced Secret is a valid key, so keys can be added per consumer.
+type APIKeyAuthentication struct {
+	In         string                        `json:"in,omitempty"`   // Either header or query, defaults to header
+	Name       string                        `json:"name,omitempty"` // Header or query param name, defaults to X-API-Key
+	SecretRefs []corev1.LocalObjectReference `json:"secretRefs"`
+}
+
+// JWTAuthentication accepts requests with a valid bearer token signed by Issuer, an OpenID Connect provider.
+// Tokens are verified with the keys the gateway discovers from <issuer>/.well-known/openid-configuration.
+type JWTAuthentication struct {
+	Issuer string `json:"issuer"`
+	// Audiences the token must be issued for one of, its aud claim can hold a single audience or a list
+	Audiences []string          `json:"audiences,omitempty"`
+	Claims    map[string]string `json:"claims,omitempty"` // Claims the token must contain with the given value
+	// JWKSCacheSeconds is how long the keys published by the issuer are cached, APIcast's default when not set
+	JWKSCacheSeconds int32 `json:"jwksCacheSeconds,omitempty"`
+	// PublicKeyRef is rejected, APIcast can't verify tokens with a static key, only with the keys of the issuer
+	PublicKeyRef *corev1.SecretKeySelector `json:"publicKeyRef,omitempty"`
 }
 
 // RateLimit is a struct used to define different types of rate limiting rules
@@ -70,38 +324,66 @@ type RateLimit struct {
 	Name       string     `json:"name"`   //TODO - This needs to reference and endpoint name currently but this relationship will reverse.
 	Source     string     `json:"source"` // Source will allow user to limit based on jwt, source ip etc
 	Type       string     `json:"type"`
-	Conditions *Condition `json:"conditions, omitempty"`
+	Conditions *Condition `json:"conditions,omitempty"`
+	// KeySources count the requests apart for each value they take, all of them together when there are several.
+	// Unlike Source, they are turned into the liquid templates for you.
+	KeySources []KeySource `json:"keySources,omitempty"`
+	// Scope defaults to endpoint, also for the rate limits of the API, which are then enforced on each Endpoint apart
+	Scope RateLimitScope `json:"scope,omitempty"`
+}
+
+// RateLimitScope is what shares the counters of a RateLimit
+type RateLimitScope string
+
+const (
+	// RateLimitScopeEndpoint counts the requests to each Endpoint apart
+	RateLimitScopeEndpoint RateLimitScope = "endpoint"
+	// RateLimitScopeAPI counts the requests to every Endpoint of the API together
+	RateLimitScopeAPI RateLimitScope = "api"
+	// RateLimitScopeGlobal counts together the requests to every API with a global rate limit of the same name,
+	// the APIs must share the same RateLimitStore URLSecretRef
+	RateLimitScopeGlobal RateLimitScope = "global"
+)
+
+// KeySource is a part of the request identifying who makes it, set exactly one field
+type KeySource struct {
+	ClientIP   bool   `json:"clientIP,omitempty"`
+	Header     string `json:"header,omitempty"`
+	JWTClaim   string `json:"jwtClaim,omitempty"` // Requires JWT authentication
+	APIKey     bool   `json:"apiKey,omitempty"`   // Requires API key authentication
+	QueryParam string `json:"queryParam,omitempty"`
 }
 
 // Condition wraps a generic rate limit condition
 type Condition struct {
-	Operator   string               `json:"operator,omitempty"`
+	Operator   string               `json:"operation,omitempty"`
 	Operations []RateLimitCondition `json:"operations"`
 }
 
-// RateLimitCondition is an interface for a type which should marshal to apicast config
+// RateLimitCondition is an interface for a type which can be rendered into apicast config,
+// its JSON encoding is the one of the CRD
 type RateLimitCondition interface {
-	MarshalJSON() ([]byte, error)
+	MarshalAPIcast() ([]byte, error)
 	DeepCopyRateLimitCondition() RateLimitCondition
 }
 
 // +k8s:deepcopy-gen:interfaces=github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1.RateLimitCondition
 type HeaderBasedCondition struct {
 	Header    string `json:"header"`
-	Operation string `json:"op, omitempty"`
+	Operation string `json:"op,omitempty"`
 	Value     string `json:"value"`
 }
 
 // +k8s:deepcopy-gen:interfaces=github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1.RateLimitCondition
 type MethodBasedCondition struct {
 	Method    string `json:"http_method"`
-	Operation string `json:"op, omitempty"`
+	Operation string `json:"op,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1.RateLimitCondition
 type PathBasedCondition struct {
 	Path      string `json:"request_path,omitempty"`
-	Operation string `json:"op, omitempty"`
+	Operation string `json:"op,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,118 @@
+package v1alpha1
+
+import "strings"
+
+const (
+	// DefaultConditionOperation is the comparison done by rate limit conditions without op
+	DefaultConditionOperation = "=="
+	// DefaultTimeUnit is the unit of rate limits without one, 100 means 100/s
+	DefaultTimeUnit = "s"
+	// DefaultAPIKeyLocation is where the API key is read from when not set
+	DefaultAPIKeyLocation = "header"
+	// DefaultAPIKeyName is the header or query param holding the API key when not set
+	DefaultAPIKeyName = "X-API-Key"
+	// DefaultRateLimitScope counts the requests to each Endpoint apart, also for the rate limits of the API,
+	// sharing the counters of the API or among APIs has to be asked for
+	DefaultRateLimitScope = RateLimitScopeEndpoint
+	// DefaultPathMatch is how Endpoint paths are matched when not set
+	DefaultPathMatch = PathMatchPrefix
+	// DefaultPolicyVersion is the version of the policies shipped with APIcast
+	DefaultPolicyVersion = "builtin"
+	// DefaultPolicyPosition is where a Policy goes in the chain when not set
+	DefaultPolicyPosition = PolicyPositionLast
+	// DefaultServiceScheme is the scheme used to call the Service of a BackendRef when not set
+	DefaultServiceScheme = "http"
+	// DefaultCacheHeader is the response header telling if the cache was hit when not set
+	DefaultCacheHeader = "X-Cache-Status"
+	// DefaultRateLimitStatus answers the requests over a rate limit when RateLimitResponse has no status
+	DefaultRateLimitStatus = 429
+)
+
+func init() {
+	SchemeBuilder.SchemeBuilder.Register(RegisterDefaults)
+}
+
+// SetDefaults_Endpoint sets how the Endpoint path is matched
+func SetDefaults_Endpoint(obj *Endpoint) {
+	if obj.PathMatch == "" {
+		obj.PathMatch = DefaultPathMatch
+	}
+}
+
+// SetDefaults_Policy sets the version and position of the Policy
+func SetDefaults_Policy(obj *Policy) {
+	obj.Version = defaultString(obj.Version, DefaultPolicyVersion)
+	if obj.Position == "" {
+		obj.Position = DefaultPolicyPosition
+	}
+}
+
+// SetDefaults_BackendRef sets the scheme used to call the Service
+func SetDefaults_BackendRef(obj *BackendRef) {
+	obj.Scheme = defaultString(obj.Scheme, DefaultServiceScheme)
+}
+
+// SetDefaults_Caching sets the header telling if the cache was hit
+func SetDefaults_Caching(obj *Caching) {
+	obj.Header = defaultString(obj.Header, DefaultCacheHeader)
+}
+
+// SetDefaults_RateLimitResponse sets the status of the response
+func SetDefaults_RateLimitResponse(obj *RateLimitResponse) {
+	if obj.Status == 0 {
+		obj.Status = DefaultRateLimitStatus
+	}
+}
+
+// SetDefaults_RateLimit fills the optional fields with the values APIcast enforces when they are missing
+func SetDefaults_RateLimit(obj *RateLimit) {
+	if obj.Limit != "" && !strings.Contains(obj.Limit, "/") {
+		obj.Limit = obj.Limit + "/" + DefaultTimeUnit
+	}
+	if obj.Scope == "" {
+		obj.Scope = DefaultRateLimitScope
+	}
+
+	switch obj.Type {
+	case "LeakyBucket":
+		obj.Burst = defaultInt(obj.Burst)
+	case "ConnectionBased":
+		obj.Burst = defaultInt(obj.Burst)
+		obj.Delay = defaultInt(obj.Delay)
+	}
+}
+
+// SetDefaults_Condition sets the comparison of every operation without one
+func SetDefaults_Condition(obj *Condition) {
+	for _, operation := range obj.Operations {
+		switch o := operation.(type) {
+		case *HeaderBasedCondition:
+			o.Operation = defaultString(o.Operation, DefaultConditionOperation)
+		case *MethodBasedCondition:
+			o.Operation = defaultString(o.Operation, DefaultConditionOperation)
+		case *PathBasedCondition:
+			o.Operation = defaultString(o.Operation, DefaultConditionOperation)
+		}
+	}
+}
+
+// SetDefaults_APIKeyAuthentication sets where the API key is read from
+func SetDefaults_APIKeyAuthentication(obj *APIKeyAuthentication) {
+	obj.In = defaultString(obj.In, DefaultAPIKeyLocation)
+	obj.Name = defaultString(obj.Name, DefaultAPIKeyName)
+}
+
+func defaultInt(value *int) *int {
+	if value != nil {
+		return value
+	}
+	zero := 0
+	return &zero
+}
+
+func defaultString(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
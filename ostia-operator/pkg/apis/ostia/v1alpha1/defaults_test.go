@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSetObjectDefaults(t *testing.T) {
+	zero := 0
+	five := 5
+
+	api := &API{}
+	err := json.Unmarshal([]byte(`{"spec":{
+		"authentication":{"apiKey":{"secretRefs":[{"name":"keys"}]}},
+		"policies":[{"name":"apicast.policy.retry"}],
+		"rate_limits":[
+			{"name":"fixed","type":"FixedWindow","limit":"100"},
+			{"name":"leaky","type":"LeakyBucket","limit":"10/m","scope":"api","conditions":{"operations":[{"http_method":"GET"}]}},
+			{"name":"conn","type":"ConnectionBased","conn":10,"delay":5,"scope":"global"}
+		],
+		"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello",
+			"authentication":{"apiKey":{"in":"query","name":"user_key","secretRefs":[{"name":"keys"}]}},
+			"rate_limits":[{"name":"per-endpoint","type":"FixedWindow","limit":"10"}]}]
+	}}`), api)
+	if err != nil {
+		t.Fatalf("error unmarshalling api - %s", err)
+	}
+
+	SetObjectDefaults_API(api)
+
+	expectLimits := []RateLimit{
+		{Name: "fixed", Type: "FixedWindow", Limit: "100/s", Scope: RateLimitScopeEndpoint},
+		{Name: "leaky", Type: "LeakyBucket", Limit: "10/m", Burst: &zero, Scope: RateLimitScopeAPI, Conditions: &Condition{
+			Operations: []RateLimitCondition{&MethodBasedCondition{Method: "GET", Operation: "=="}},
+		}},
+		{Name: "conn", Type: "ConnectionBased", Conn: api.Spec.RateLimits[2].Conn, Burst: &zero, Delay: &five, Scope: RateLimitScopeGlobal},
+	}
+	if !reflect.DeepEqual(expectLimits, api.Spec.RateLimits) {
+		t.Errorf("unexpected rate limit defaults - %#v", api.Spec.RateLimits)
+	}
+
+	expectEndpointLimits := []RateLimit{{Name: "per-endpoint", Type: "FixedWindow", Limit: "10/s", Scope: RateLimitScopeEndpoint}}
+	if !reflect.DeepEqual(expectEndpointLimits, api.Spec.Endpoints[0].RateLimits) {
+		t.Errorf("unexpected endpoint rate limit defaults - %#v", api.Spec.Endpoints[0].RateLimits)
+	}
+
+	if apiKey := api.Spec.Authentication.APIKey; apiKey.In != "header" || apiKey.Name != "X-API-Key" {
+		t.Errorf("unexpected api key defaults - %#v", apiKey)
+	}
+
+	if apiKey := api.Spec.Endpoints[0].Authentication.APIKey; apiKey.In != "query" || apiKey.Name != "user_key" {
+		t.Errorf("api key values should not be overridden - %#v", apiKey)
+	}
+
+	if policy := api.Spec.Policies[0]; policy.Version != "builtin" || policy.Position != PolicyPositionLast {
+		t.Errorf("unexpected policy defaults - %#v", policy)
+	}
+
+	if pathMatch := api.Spec.Endpoints[0].PathMatch; pathMatch != PathMatchPrefix {
+		t.Errorf("unexpected path match default - %s", pathMatch)
+	}
+}
@@ -1,4 +1,5 @@
 // Package v1alpha1 contains API Schema definitions for the ostia v1alpha1 API group
 // +k8s:deepcopy-gen=package,register
+// +k8s:defaulter-gen=TypeMeta
 // +groupName=ostia.3scale.net
 package v1alpha1
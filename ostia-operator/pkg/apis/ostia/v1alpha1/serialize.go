@@ -9,7 +9,8 @@ import (
 	"strings"
 )
 
-func (hc HeaderBasedCondition) MarshalJSON() ([]byte, error) {
+// MarshalAPIcast renders the condition as an operation of the APIcast rate limit policy
+func (hc HeaderBasedCondition) MarshalAPIcast() ([]byte, error) {
 	var op string
 	if hc.Header == "" || hc.Value == "" {
 		return nil, errors.New("header and header value required for header based condition")
@@ -34,7 +35,8 @@ func (hc HeaderBasedCondition) MarshalJSON() ([]byte, error) {
 	return b, nil
 }
 
-func (mc MethodBasedCondition) MarshalJSON() ([]byte, error) {
+// MarshalAPIcast renders the condition as an operation of the APIcast rate limit policy
+func (mc MethodBasedCondition) MarshalAPIcast() ([]byte, error) {
 	op, err := parseOp(mc.Operation)
 	if err != nil {
 		return nil, err
@@ -63,7 +65,8 @@ func (mc MethodBasedCondition) MarshalJSON() ([]byte, error) {
 	return b, nil
 }
 
-func (pc PathBasedCondition) MarshalJSON() ([]byte, error) {
+// MarshalAPIcast renders the condition as an operation of the APIcast rate limit policy
+func (pc PathBasedCondition) MarshalAPIcast() ([]byte, error) {
 	var op string
 	condition := make(map[string]string)
 
@@ -177,7 +180,7 @@ func parseOp(op string) (string, error) {
 			return "", errors.New("unrecognised operand provided")
 		}
 	} else {
-		op = "=="
+		op = DefaultConditionOperation
 	}
 	return op, nil
 }
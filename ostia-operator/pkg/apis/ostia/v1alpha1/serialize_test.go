@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -82,7 +83,7 @@ func TestUnmarshalCondition(t *testing.T) {
 	}
 }
 
-func TestMarshalJSON(t *testing.T) {
+func TestMarshalAPIcast(t *testing.T) {
 	inputs := []struct {
 		obj            RateLimitCondition
 		expectContents string
@@ -135,7 +136,7 @@ func TestMarshalJSON(t *testing.T) {
 	}
 
 	for _, input := range inputs {
-		res, err := input.obj.MarshalJSON()
+		res, err := input.obj.MarshalAPIcast()
 		if input.expectErr && err != nil {
 			continue
 		} else if err != nil {
@@ -147,3 +148,21 @@ func TestMarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestConditionRoundTrip(t *testing.T) {
+	crd := `{"operation":"or","operations":[{"http_method":"GET","op":"=="},{"request_path":"/test","op":"!="},{"header":"TEST","op":"==","value":"test"}]}`
+
+	c := &Condition{}
+	if err := json.Unmarshal([]byte(crd), c); err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	// The stored object keeps the CRD form, APIcast operations are only rendered into the configuration
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	if string(b) != crd {
+		t.Errorf("unexpected condition marshalled - %s", b)
+	}
+}
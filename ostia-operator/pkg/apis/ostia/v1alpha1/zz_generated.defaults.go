@@ -12,5 +12,62 @@ import (
 // Public to allow building arbitrary schemes.
 // All generated defaulters are covering - they call all nested defaulters.
 func RegisterDefaults(scheme *runtime.Scheme) error {
+	scheme.AddTypeDefaultingFunc(&API{}, func(obj interface{}) { SetObjectDefaults_API(obj.(*API)) })
+	scheme.AddTypeDefaultingFunc(&APIList{}, func(obj interface{}) { SetObjectDefaults_APIList(obj.(*APIList)) })
 	return nil
 }
+
+func SetObjectDefaults_API(in *API) {
+	for i := range in.Spec.Endpoints {
+		a := &in.Spec.Endpoints[i]
+		SetDefaults_Endpoint(a)
+		for j := range a.RateLimits {
+			b := &a.RateLimits[j]
+			SetDefaults_RateLimit(b)
+			if b.Conditions != nil {
+				SetDefaults_Condition(b.Conditions)
+			}
+		}
+		if a.Authentication != nil {
+			if a.Authentication.APIKey != nil {
+				SetDefaults_APIKeyAuthentication(a.Authentication.APIKey)
+			}
+		}
+		for j := range a.Policies {
+			b := &a.Policies[j]
+			SetDefaults_Policy(b)
+		}
+		if a.BackendRef != nil {
+			SetDefaults_BackendRef(a.BackendRef)
+		}
+		if a.Caching != nil {
+			SetDefaults_Caching(a.Caching)
+		}
+	}
+	for i := range in.Spec.RateLimits {
+		a := &in.Spec.RateLimits[i]
+		SetDefaults_RateLimit(a)
+		if a.Conditions != nil {
+			SetDefaults_Condition(a.Conditions)
+		}
+	}
+	if in.Spec.Authentication != nil {
+		if in.Spec.Authentication.APIKey != nil {
+			SetDefaults_APIKeyAuthentication(in.Spec.Authentication.APIKey)
+		}
+	}
+	for i := range in.Spec.Policies {
+		a := &in.Spec.Policies[i]
+		SetDefaults_Policy(a)
+	}
+	if in.Spec.RateLimitResponse != nil {
+		SetDefaults_RateLimitResponse(in.Spec.RateLimitResponse)
+	}
+}
+
+func SetObjectDefaults_APIList(in *APIList) {
+	for i := range in.Items {
+		a := &in.Items[i]
+		SetObjectDefaults_API(a)
+	}
+}
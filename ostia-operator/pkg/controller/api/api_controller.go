@@ -1,11 +1,17 @@
 package api
 
 import (
+	"context"
+
 	"github.com/3scale/ostia/ostia-operator/pkg/apicast"
 	ostiav1alpha1 "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -35,6 +41,8 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	cl := mgr.GetClient()
+
 	// Create a new controller
 	c, err := controller.New("api-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -57,9 +65,112 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch the Ingress of exposed APIs to report when it gets admitted
+	err = c.Watch(&source.Kind{Type: &extensions.Ingress{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &ostiav1alpha1.API{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch the Secret holding the configuration so it is restored when changed by someone else
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &ostiav1alpha1.API{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Secrets referenced by an API so the configuration is rendered again
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+			return apisReferencing(cl, obj.Meta.GetNamespace(), obj.Meta.GetName(), apicast.ReferencedSecrets)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to ConfigMaps referenced by an API so the configuration is rendered again
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+			return apisReferencing(cl, obj.Meta.GetNamespace(), obj.Meta.GetName(), apicast.ReferencedConfigMaps)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	watchNamespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+
+	// Watch the Services used as backend to report when they go missing, only the watched namespaces are cached
+	err = c.Watch(&source.Kind{Type: &corev1.Service{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+			return apisUsingBackend(cl, watchNamespace, types.NamespacedName{Name: obj.Meta.GetName(), Namespace: obj.Meta.GetNamespace()})
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// apisReferencing returns a request for every API in namespace whose referenced objects include name
+func apisReferencing(cl client.Client, namespace string, name string, referenced func(*ostiav1alpha1.API) []string) []reconcile.Request {
+	var requests []reconcile.Request
+
+	apis := &ostiav1alpha1.APIList{}
+	if err := cl.List(context.TODO(), client.InNamespace(namespace), apis); err != nil {
+		log.Error(err, "Failed to list APIs", "Namespace", namespace)
+		return requests
+	}
+
+	for i := range apis.Items {
+		api := &apis.Items[i]
+		for _, ref := range referenced(api) {
+			if ref == name {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: api.Name, Namespace: api.Namespace},
+				})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// apisUsingBackend returns a request for every API in watchNamespace, every namespace when empty, calling the Service
+func apisUsingBackend(cl client.Client, watchNamespace string, service types.NamespacedName) []reconcile.Request {
+	var requests []reconcile.Request
+
+	apis := &ostiav1alpha1.APIList{}
+	if err := cl.List(context.TODO(), client.InNamespace(watchNamespace), apis); err != nil {
+		log.Error(err, "Failed to list APIs", "Namespace", watchNamespace)
+		return requests
+	}
+
+	for i := range apis.Items {
+		api := &apis.Items[i]
+		for _, backend := range apicast.ReferencedBackends(api) {
+			if backend == service {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: api.Name, Namespace: api.Namespace},
+				})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
 var _ reconcile.Reconciler = &ReconcileAPI{}
 
 // ReconcileAPI reconciles a API object
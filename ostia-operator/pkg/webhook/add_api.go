@@ -0,0 +1,10 @@
+package webhook
+
+import (
+	"github.com/3scale/ostia/ostia-operator/pkg/webhook/api"
+)
+
+func init() {
+	// AddToServerFuncs is a list of functions to create webhooks and register them in the admission server.
+	AddToServerFuncs = append(AddToServerFuncs, api.MutatingWebhook, api.ValidatingWebhook)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	ostiav1alpha1 "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"github.com/appscode/jsonpatch"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/builder"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// MutatingWebhook returns a Webhook storing the API objects with all their defaults set,
+// so the stored object shows the values APIcast enforces
+func MutatingWebhook(mgr manager.Manager) (webhook.Webhook, error) {
+	return builder.NewWebhookBuilder().
+		Name("mutating.apis.ostia.3scale.net").
+		Path("/mutate-apis").
+		Mutating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		FailurePolicy(admissionregistrationv1beta1.Fail).
+		ForType(&ostiav1alpha1.API{}).
+		WithManager(mgr).
+		Handlers(&apiDefaulter{}).
+		Build()
+}
+
+// apiDefaulter sets the defaults registered for the v1alpha1 API type
+type apiDefaulter struct {
+	decoder types.Decoder
+}
+
+var _ admission.Handler = &apiDefaulter{}
+
+// Handle patches the API with its default values
+func (d *apiDefaulter) Handle(ctx context.Context, req types.Request) types.Response {
+	api := &ostiav1alpha1.API{}
+
+	if err := d.decoder.Decode(req, api); err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	ostiav1alpha1.SetObjectDefaults_API(api)
+
+	defaulted, err := json.Marshal(api)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	// The patch is made against the object as sent, the decoded copy would already hide what was left out
+	patches, err := jsonpatch.CreatePatch(req.AdmissionRequest.Object.Raw, defaulted)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	return types.Response{
+		Patches: patches,
+		Response: &admissionv1beta1.AdmissionResponse{
+			Allowed:   true,
+			PatchType: &patchType,
+		},
+	}
+}
+
+// InjectDecoder injects the decoder into the apiDefaulter
+func (d *apiDefaulter) InjectDecoder(decoder types.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
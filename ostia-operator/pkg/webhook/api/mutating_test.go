@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ostiav1alpha1 "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// admissionRequest wraps the raw object the way the API server sends it to the webhooks
+func admissionRequest(raw string) types.Request {
+	return types.Request{AdmissionRequest: &admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(raw)},
+	}}
+}
+
+func testDecoder(t *testing.T) types.Decoder {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := ostiav1alpha1.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatalf("unexpected error - %s", err)
+	}
+
+	return decoder
+}
+
+func TestDefaulterPatch(t *testing.T) {
+	defaulter := &apiDefaulter{decoder: testDecoder(t)}
+
+	resp := defaulter.Handle(context.TODO(), admissionRequest(`{
+		"apiVersion":"ostia.3scale.net/v1alpha1","kind":"API","metadata":{"name":"hello","namespace":"apis"},
+		"spec":{"hostname":"hello.example.com","endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello"}],
+			"rate_limits":[{"name":"gets","type":"FixedWindow","limit":"100",
+				"conditions":{"operations":[{"http_method":"GET"},{"header":"X-Tenant","value":"a"}],"operation":"and"}}]}
+	}`))
+
+	if !resp.Response.Allowed {
+		t.Fatalf("unexpected rejection - %#v", resp.Response.Result)
+	}
+
+	patched := make(map[string]interface{})
+	for _, operation := range resp.Patches {
+		if strings.Contains(operation.Path, "/left") || strings.Contains(operation.Path, "/right") {
+			t.Errorf("patch renders the APIcast form of the conditions - %#v", operation)
+		}
+		patched[operation.Path] = operation.Value
+	}
+
+	expected := map[string]interface{}{
+		"/spec/rate_limits/0/limit":                      "100/s",
+		"/spec/rate_limits/0/scope":                      "endpoint",
+		"/spec/rate_limits/0/conditions/operations/0/op": "==",
+		"/spec/rate_limits/0/conditions/operations/1/op": "==",
+		"/spec/endpoints/0/pathMatch":                    "prefix",
+	}
+	for path, value := range expected {
+		if patched[path] != value {
+			t.Errorf("expected patch of %s to %v, got %v", path, value, patched[path])
+		}
+	}
+	if _, ok := patched["/spec/rate_limits/0/conditions/operation"]; ok {
+		t.Errorf("patch changes the condition operator - %#v", resp.Patches)
+	}
+}
+
+func TestDefaulterPatchDefaulted(t *testing.T) {
+	defaulter := &apiDefaulter{decoder: testDecoder(t)}
+
+	resp := defaulter.Handle(context.TODO(), admissionRequest(`{
+		"apiVersion":"ostia.3scale.net/v1alpha1","kind":"API","metadata":{"name":"hello","namespace":"apis"},
+		"spec":{"hostname":"hello.example.com","endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello","pathMatch":"prefix"}],
+			"rate_limits":[{"name":"gets","type":"FixedWindow","limit":"100/m","scope":"api",
+				"conditions":{"operations":[{"http_method":"GET","op":"!="}]}}]}
+	}`))
+
+	if !resp.Response.Allowed {
+		t.Fatalf("unexpected rejection - %#v", resp.Response.Result)
+	}
+
+	for _, operation := range resp.Patches {
+		if strings.HasPrefix(operation.Path, "/spec/rate_limits/0/conditions") ||
+			operation.Path == "/spec/rate_limits/0/limit" || operation.Path == "/spec/endpoints/0/pathMatch" {
+			t.Errorf("unexpected patch of a set field - %#v", operation)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/3scale/ostia/ostia-operator/pkg/apicast/standalone"
+	ostiav1alpha1 "github.com/3scale/ostia/ostia-operator/pkg/apis/ostia/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/builder"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+var log = logf.Log.WithName("webhook_api")
+
+// ValidatingWebhook returns a Webhook rejecting API objects which can't be turned into an APIcast configuration
+func ValidatingWebhook(mgr manager.Manager) (webhook.Webhook, error) {
+	watchNamespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	return builder.NewWebhookBuilder().
+		Name("validating.apis.ostia.3scale.net").
+		Path("/validate-apis").
+		Validating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		FailurePolicy(admissionregistrationv1beta1.Fail).
+		ForType(&ostiav1alpha1.API{}).
+		WithManager(mgr).
+		Handlers(&apiValidator{client: mgr.GetClient(), watchNamespace: watchNamespace}).
+		Build()
+}
+
+// apiValidator validates API objects with the same rules used to render the APIcast configuration
+type apiValidator struct {
+	client  client.Client
+	decoder types.Decoder
+	// watchNamespace is the only namespace the operator sees, every namespace when empty
+	watchNamespace string
+}
+
+var _ admission.Handler = &apiValidator{}
+
+// Handle rejects the API with the list of invalid fields
+func (v *apiValidator) Handle(ctx context.Context, req types.Request) types.Response {
+	api := &ostiav1alpha1.API{}
+
+	if err := v.decoder.Decode(req, api); err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	errs := standalone.Validate(api)
+
+	accessErrs, err := v.checkBackendAccess(ctx, req, api)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	errs = append(errs, accessErrs...)
+
+	if len(errs) == 0 {
+		return admission.ValidationResponse(true, "")
+	}
+
+	log.Info("Rejecting invalid API", "Namespace", api.Namespace, "Name", api.Name, "Errors", errs.ToAggregate().Error())
+
+	status := apierrors.NewInvalid(schema.GroupKind{Group: ostiav1alpha1.SchemeGroupVersion.Group, Kind: "API"}, api.Name, errs).Status()
+
+	return types.Response{
+		Response: &admissionv1beta1.AdmissionResponse{
+			Allowed: false,
+			Result:  &status,
+		},
+	}
+}
+
+// checkBackendAccess forbids BackendRefs to Services of other namespaces the requesting user can't get,
+// so APIs can't be used to reach Services their authors have no access to. Namespaces not watched by the
+// operator are forbidden too, their Services can't be checked.
+func (v *apiValidator) checkBackendAccess(ctx context.Context, req types.Request, api *ostiav1alpha1.API) (field.ErrorList, error) {
+	var errs field.ErrorList
+	user := req.AdmissionRequest.UserInfo
+
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	for i, endpoint := range api.Spec.Endpoints {
+		ref := endpoint.BackendRef
+		if ref == nil {
+			continue
+		}
+
+		namespace := standalone.BackendNamespace(api, ref)
+		if namespace == api.Namespace {
+			continue
+		}
+
+		path := field.NewPath("spec", "endpoints").Index(i).Child("backendRef", "namespace")
+		if v.watchNamespace != "" && namespace != v.watchNamespace {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("namespace %s is not watched by the operator", namespace)))
+			continue
+		}
+
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user.Username,
+				UID:    user.UID,
+				Groups: user.Groups,
+				Extra:  extra,
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      "get",
+					Resource:  "services",
+					Name:      ref.Name,
+				},
+			},
+		}
+
+		if err := v.client.Create(ctx, review); err != nil {
+			return errs, err
+		}
+
+		if !review.Status.Allowed {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("user %s can't get service %s in namespace %s", user.Username, ref.Name, namespace)))
+		}
+	}
+
+	return errs, nil
+}
+
+// InjectDecoder injects the decoder into the apiValidator
+func (v *apiValidator) InjectDecoder(d types.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// accessReviewClient answers the SubjectAccessReviews, only the users in allowed can get the Services
+type accessReviewClient struct {
+	client.Client
+	allowed map[string]bool
+	reviews []authorizationv1.SubjectAccessReviewSpec
+}
+
+func (c *accessReviewClient) Create(ctx context.Context, obj runtime.Object) error {
+	review, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj)
+	}
+	c.reviews = append(c.reviews, review.Spec)
+	review.Status.Allowed = c.allowed[review.Spec.User]
+	return nil
+}
+
+// rejectedFields returns the field paths the response rejects the API for
+func rejectedFields(resp types.Response) []string {
+	var fields []string
+
+	if resp.Response.Result == nil || resp.Response.Result.Details == nil {
+		return fields
+	}
+	for _, cause := range resp.Response.Result.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+
+	return fields
+}
+
+func TestValidatorRejection(t *testing.T) {
+	validator := &apiValidator{decoder: testDecoder(t), client: &accessReviewClient{}}
+
+	resp := validator.Handle(context.TODO(), admissionRequest(`{
+		"apiVersion":"ostia.3scale.net/v1alpha1","kind":"API","metadata":{"name":"hello","namespace":"apis"},
+		"spec":{"hostname":"hello.example.com",
+			"endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello"},{"name":"hello","host":"echo-api","path":"/bye"}],
+			"rate_limits":[{"name":"a","type":"Sliding","limit":"10/m"},{"name":"b","type":"FixedWindow","limit":"10/week"}]}
+	}`))
+
+	if resp.Response.Allowed {
+		t.Fatal("expected the invalid API to be rejected")
+	}
+
+	expected := []string{"spec.rate_limits[0].type", "spec.rate_limits[1].limit", "spec.endpoints[1].name", "spec.endpoints[1].host"}
+	if fields := rejectedFields(resp); !reflect.DeepEqual(expected, fields) {
+		t.Errorf("expected rejected fields %v, got %v", expected, fields)
+	}
+
+	resp = validator.Handle(context.TODO(), admissionRequest(`{
+		"apiVersion":"ostia.3scale.net/v1alpha1","kind":"API","metadata":{"name":"hello","namespace":"apis"},
+		"spec":{"hostname":"hello.example.com","endpoints":[{"name":"hello","host":"https://echo-api.3scale.net","path":"/hello"}]}
+	}`))
+
+	if !resp.Response.Allowed {
+		t.Errorf("unexpected rejection - %#v", resp.Response.Result)
+	}
+}
+
+func TestValidatorBackendAccess(t *testing.T) {
+	api := `{
+		"apiVersion":"ostia.3scale.net/v1alpha1","kind":"API","metadata":{"name":"hello","namespace":"apis"},
+		"spec":{"hostname":"hello.example.com","endpoints":[
+			{"name":"local","backendRef":{"name":"orders","port":8080},"path":"/orders"},
+			{"name":"shared","backendRef":{"name":"carts","namespace":"shop","port":8080},"path":"/carts"}]}
+	}`
+
+	inputs := []struct {
+		user           string
+		watchNamespace string
+		expectAllowed  bool
+		expectReviews  int
+	}{
+		{user: "alice", expectAllowed: true, expectReviews: 1},
+		{user: "bob", expectAllowed: false, expectReviews: 1},
+		// The Services of namespaces not watched by the operator can't be checked, so no one can use them
+		{user: "alice", watchNamespace: "apis", expectAllowed: false, expectReviews: 0},
+	}
+
+	for _, input := range inputs {
+		cl := &accessReviewClient{allowed: map[string]bool{"alice": true}}
+		validator := &apiValidator{decoder: testDecoder(t), client: cl, watchNamespace: input.watchNamespace}
+
+		req := admissionRequest(api)
+		req.AdmissionRequest.UserInfo = authenticationv1.UserInfo{Username: input.user, Groups: []string{"developers"}}
+
+		resp := validator.Handle(context.TODO(), req)
+
+		if resp.Response.Allowed != input.expectAllowed {
+			t.Errorf("%s watching %q - expected allowed to be %t - %#v", input.user, input.watchNamespace, input.expectAllowed, resp.Response.Result)
+		}
+		if !input.expectAllowed {
+			expected := []string{"spec.endpoints[1].backendRef.namespace"}
+			if fields := rejectedFields(resp); !reflect.DeepEqual(expected, fields) {
+				t.Errorf("expected rejected fields %v, got %v", expected, fields)
+			}
+		}
+
+		// Only the Service of the other namespace is reviewed, as the requesting user
+		if len(cl.reviews) != input.expectReviews {
+			t.Fatalf("expected %d access reviews, got %#v", input.expectReviews, cl.reviews)
+		}
+		for _, review := range cl.reviews {
+			expected := authorizationv1.ResourceAttributes{Namespace: "shop", Verb: "get", Resource: "services", Name: "carts"}
+			if review.User != input.user || !reflect.DeepEqual(review.Groups, []string{"developers"}) || *review.ResourceAttributes != expected {
+				t.Errorf("unexpected access review - %#v", review)
+			}
+		}
+	}
+}
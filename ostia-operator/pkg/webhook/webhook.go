@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Change below variables to serve the admission webhooks on a different port or from other objects.
+var (
+	serverPort    int32 = 9876
+	serverCertDir       = "/tmp/ostia-webhook-certs"
+	serviceName         = "ostia-operator-webhook"
+	secretName          = "ostia-operator-webhook-cert"
+)
+
+// AddToServerFuncs is a list of functions to create Webhooks and register them in the admission server
+var AddToServerFuncs []func(manager.Manager) (webhook.Webhook, error)
+
+// AddToManager creates the admission server for the operator namespace, registers all Webhooks and adds it to the Manager.
+// The server installs its own webhook configurations, Service and certificate Secret.
+func AddToManager(m manager.Manager, namespace string) error {
+	server, err := webhook.NewServer("ostia-admission-server", m, webhook.ServerOptions{
+		Port:    serverPort,
+		CertDir: serverCertDir,
+		BootstrapOptions: &webhook.BootstrapOptions{
+			MutatingWebhookConfigName:   "ostia-operator-mutating",
+			ValidatingWebhookConfigName: "ostia-operator-validating",
+			Secret:                      &types.NamespacedName{Name: secretName, Namespace: namespace},
+			Service: &webhook.Service{
+				Name:      serviceName,
+				Namespace: namespace,
+				Selectors: map[string]string{"name": "ostia-operator"},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var webhooks []webhook.Webhook
+	for _, f := range AddToServerFuncs {
+		wh, err := f(m)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	return server.Register(webhooks...)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/operator-framework/operator-sdk/pkg/test/e2eutil"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -178,8 +179,8 @@ func waitForAPI(t *testing.T, f *framework.Framework, API *operator.API) error {
 
 		for _, condition := range api.Status.Conditions {
 			switch condition.Type {
-			case "Ready":
-				return condition.Status == "true", nil
+			case operator.ConditionReady:
+				return condition.Status == corev1.ConditionTrue, nil
 			}
 		}
 